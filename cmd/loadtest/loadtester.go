@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"github.com/castaneai/openmatch-local-dev/omutils/record"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var logger = logging.Component("loadtest")
+
+// loadtester creates and watches tickets on behalf of the load-testing modes (scenario, population and replay).
+type loadtester struct {
+	omFrontend     pb.FrontendServiceClient
+	patience       *patience
+	recorder       *record.Recorder
+	reportInterval time.Duration
+}
+
+// createTicket returns the created ticket, or nil if it failed.
+func (lt *loadtester) createTicket(ctx context.Context, ticket *pb.Ticket, st *stats) *pb.Ticket {
+	created, err := lt.omFrontend.CreateTicket(ctx, &pb.CreateTicketRequest{Ticket: ticket})
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to create ticket", "error", err)
+			st.TicketFailed()
+		}
+		return nil
+	}
+	logger.Debug("ticket created", logging.TicketID(created.Id))
+	st.TicketCreated()
+	if err := lt.recorder.TicketCreated(created); err != nil {
+		logger.Error("failed to record ticket", logging.TicketID(created.Id), "error", err)
+	}
+	return created
+}
+
+// watchTickets waits for the ticket to be assigned, and returns nil if the ticket is abandoned or not assigned.
+func (lt *loadtester) watchTickets(ctx context.Context, ticket *pb.Ticket, st *stats) *pb.Assignment {
+	start := time.Now()
+	watchCtx := ctx
+	if lt.patience.Enabled() {
+		var cancel context.CancelFunc
+		watchCtx, cancel = context.WithTimeout(ctx, lt.patience.Sample())
+		defer cancel()
+	}
+	assignment, err := omutils.WaitForAssignment(watchCtx, lt.omFrontend, ticket.Id)
+	if err != nil {
+		if ctx.Err() == nil && watchCtx.Err() != nil {
+			lt.abandonTicket(ticket, time.Since(start), st)
+			return nil
+		}
+		if ctx.Err() == nil {
+			logger.Error("failed to watch assignments", logging.TicketID(ticket.Id), "error", err)
+		}
+		return nil
+	}
+	st.TicketAssigned(time.Since(start))
+	logger.Debug("ticket assigned", logging.TicketID(ticket.Id), "connection", assignment.Connection, "latency", time.Since(start))
+	if err := lt.recorder.Assignment([]string{ticket.Id}, assignment); err != nil {
+		logger.Error("failed to record assignment", logging.TicketID(ticket.Id), "error", err)
+	}
+	return assignment
+}
+
+// abandonTicket simulates a player who cancels matchmaking before being assigned.
+func (lt *loadtester) abandonTicket(ticket *pb.Ticket, waited time.Duration, st *stats) {
+	if _, err := lt.omFrontend.DeleteTicket(context.Background(), &pb.DeleteTicketRequest{TicketId: ticket.Id}); err != nil {
+		logger.Error("failed to delete ticket", logging.TicketID(ticket.Id), "error", err)
+		return
+	}
+	st.TicketAbandoned()
+	logger.Debug("ticket abandoned", logging.TicketID(ticket.Id), "waited", waited.Round(time.Millisecond))
+}
+
+// reportEvery calls report at the report interval until ctx is done.
+func (lt *loadtester) reportEvery(ctx context.Context, report func()) {
+	ticker := time.NewTicker(lt.reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report()
+		}
+	}
+}
+
+// waitTickets waits for the outcome of the remaining tickets before the final report.
+func waitTickets(ctx context.Context, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+}
@@ -2,16 +2,17 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
-	"io"
-	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
 	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"github.com/castaneai/openmatch-local-dev/omutils/quality"
+	"github.com/castaneai/openmatch-local-dev/omutils/record"
 	"open-match.dev/open-match/pkg/pb"
 )
 
@@ -27,67 +28,148 @@ func main() {
 	var rps float64
 	var frontendAddr, backendAddr, matchFunction string
 	var builtinDirector bool
+	var patienceDist string
+	var patienceMean, patienceStddev, reportInterval time.Duration
+	var scenarioFile, recordFile, replayFile string
+	ph := &phase{Name: "main"}
+	population := &populationConfig{}
+	tlsConfig := &omutils.TLSConfig{}
+	tlsConfig.RegisterFlags(flag.CommandLine)
 	flag.Float64Var(&rps, "rps", 1.0, "RPS (request per second)")
 	flag.StringVar(&frontendAddr, "frontend", "localhost:50504", "An address of Open Match frontend")
 	flag.StringVar(&backendAddr, "backend", "localhost:50505", "An address of Open Match backend")
 	flag.StringVar(&matchFunction, "matchfunction", "matchfunction-simple1vs1", "An name of Match Function")
 	flag.BoolVar(&builtinDirector, "builtin-director", true, "Enabling built-in director")
+	flag.StringVar(&patienceDist, "patience-dist", "exponential", "A distribution of player patience (fixed, uniform, exponential or normal)")
+	flag.DurationVar(&patienceMean, "patience", 0, "A mean time players wait for an assignment before cancelling matchmaking (0 means players never cancel)")
+	flag.DurationVar(&patienceStddev, "patience-stddev", 10*time.Second, "A standard deviation of player patience (normal distribution only)")
+	flag.DurationVar(&reportInterval, "report-interval", 10*time.Second, "An interval of reporting statistics")
+	flag.StringVar(&ph.Shape, "shape", "constant", "A shape of load (constant, ramp, step or spike)")
+	flag.StringVar(&ph.Arrival, "arrival", "uniform", "A distribution of ticket arrivals (uniform or poisson)")
+	flag.DurationVar((*time.Duration)(&ph.Duration), "duration", 0, "A duration of load-testing (0 means forever; required for ramp, step and spike)")
+	flag.Float64Var(&ph.ToRPS, "to-rps", 0, "RPS at the end of ramp or step shape")
+	flag.IntVar(&ph.Steps, "steps", 5, "A number of steps of step shape")
+	flag.Float64Var(&ph.SpikeRPS, "spike-rps", 0, "RPS during the spike of spike shape")
+	flag.DurationVar((*time.Duration)(&ph.SpikeDuration), "spike-duration", 0, "A duration of the spike of spike shape")
+	flag.StringVar(&scenarioFile, "scenario", "", "A path to scenario file (JSON) describing load phases; overrides load shape flags")
+	flag.IntVar(&population.Players, "players", 0, "A number of simulated players who re-queue after each session (0 means open-loop load by RPS)")
+	flag.IntVar(&population.MaxPartySize, "max-party-size", 1, "A maximum number of players in a party (population mode only; only 1 is supported for now)")
+	flag.StringVar(&population.Regions, "regions", "asia,us,eu", "Comma-separated regions of players (population mode only)")
+	flag.DurationVar(&population.SessionLength, "session", 30*time.Second, "A mean length of a game session (population mode only)")
+	flag.DurationVar(&population.RequeueDelay, "requeue-delay", 5*time.Second, "A delay before players re-queue after a session (population mode only)")
+	flag.StringVar(&recordFile, "record", "", "A path to record ticket creations, proposals and assignments (JSON Lines)")
+	flag.StringVar(&replayFile, "replay", "", "A path to record file to replay its ticket stream; overrides load shape and population flags")
 	flag.Parse()
+	logging.SetupFromEnv()
 
-	log.Printf("open match load-testing (rps: %.2f, frontend addr: %s)", rps, frontendAddr)
+	pt, err := newPatience(patienceDist, patienceMean, patienceStddev)
+	if err != nil {
+		logging.Fatal(logger, "invalid patience", "error", err)
+	}
+	sc := &scenario{Phases: []*phase{ph}}
+	if scenarioFile != "" {
+		sc, err = loadScenario(scenarioFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to load scenario", "error", err)
+		}
+	} else {
+		ph.RPS = rps
+		if err := ph.validate(); err != nil {
+			logging.Fatal(logger, "invalid load shape", "error", err)
+		}
+	}
+	if population.Players > 0 {
+		if err := population.validate(); err != nil {
+			logging.Fatal(logger, "invalid population", "error", err)
+		}
+	}
+	var replayEvents []*record.Event
+	if replayFile != "" {
+		replayEvents, err = record.ReadEvents(replayFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to read replay file", "error", err)
+		}
+	}
+	var recorder *record.Recorder
+	if recordFile != "" {
+		recorder, err = record.NewRecorder(recordFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to start recording", "error", err)
+		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				logger.Error("failed to close record file", "error", err)
+			}
+		}()
+	}
+	// fatal closes the record file before exiting, since os.Exit skips the deferred Close.
+	fatal := func(msg string, args ...any) {
+		if err := recorder.Close(); err != nil {
+			logger.Error("failed to close record file", "error", err)
+		}
+		logging.Fatal(logger, msg, args...)
+	}
+	logger.Info("open match load-testing", "frontend", frontendAddr, "patience", pt.String())
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	if builtinDirector {
-		director, err := omutils.NewTestDirector(backendAddr, matchProfile, matchFunction)
+		// The quality of matches made by the match function, to compare algorithms under the same load.
+		qs := &quality.Summary{}
+		capacity := capacityOf(matchFunction)
+		defer func() { logger.Info("result", "quality", qs.String()) }()
+		go func() {
+			ticker := time.NewTicker(reportInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					logger.Info("stats", "quality", qs.String())
+				}
+			}
+		}()
+		director, err := omutils.NewTestDirector(backendAddr, matchProfile, matchFunction,
+			omutils.WithClientOptions(omutils.WithTLS(tlsConfig)),
+			omutils.WithObservers(func(match *pb.Match, _ *pb.Assignment) {
+				qs.Add(quality.Evaluate(match, capacity, time.Now()))
+			}, recorder.ObserveMatch))
 		if err != nil {
-			log.Fatalf("failed to new test director: %+v", err)
+			fatal("failed to create test director", "error", err)
 		}
 		go func() {
 			if err := director.Run(ctx, 2*time.Second); err != nil {
-				log.Printf("failed to run director: %+v", err)
+				logger.Error("failed to run director", "error", err)
 			}
 		}()
 	}
 
-	omFrontend, err := omutils.NewOMFrontendClient(frontendAddr)
+	omFrontend, err := omutils.NewOMFrontendClient(frontendAddr, omutils.WithTLS(tlsConfig))
 	if err != nil {
-		log.Fatalf("failed to new om frontend client: %+v", err)
+		fatal("failed to create om frontend client", "error", err)
 	}
-	tick := time.Duration(1.0 / rps * float64(time.Second))
-	ticker := time.NewTicker(tick)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			ticket, err := omFrontend.CreateTicket(ctx, &pb.CreateTicketRequest{
-				Ticket: &pb.Ticket{},
-			})
-			if err != nil {
-				log.Printf("failed to create ticket: %+v", err)
-				continue
-			}
-			log.Printf("ticket created: %s", ticket.Id)
-			go watchTickets(ctx, omFrontend, ticket)
-		}
+	lt := &loadtester{
+		omFrontend:     omFrontend,
+		patience:       pt,
+		recorder:       recorder,
+		reportInterval: reportInterval,
+	}
+	switch {
+	case replayEvents != nil:
+		lt.runReplay(ctx, replayEvents)
+	case population.Players > 0:
+		lt.runPopulation(ctx, population)
+	default:
+		lt.runScenario(ctx, sc)
 	}
 }
 
-func watchTickets(ctx context.Context, omFrontend pb.FrontendServiceClient, ticket *pb.Ticket) {
-	stream, err := omFrontend.WatchAssignments(ctx, &pb.WatchAssignmentsRequest{TicketId: ticket.Id})
-	if err != nil {
-		return
-	}
-	resp, err := stream.Recv()
-	if errors.Is(err, io.EOF) {
-		return
-	}
-	if err != nil {
-		log.Printf("failed to recv watch assignments: %+v", err)
-		return
+// capacityOf returns the number of players in a game server of the match function.
+func capacityOf(matchFunction string) int {
+	if matchFunction == "matchfunction-simple1vs1" {
+		return mmlogic.Simple1vs1PlayersPerMatch
 	}
-	log.Printf("ticket %s assigned to %s", ticket.Id, resp.Assignment.Connection)
+	return omutils.PlayersPerMatch
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// patience decides how long a player waits for an assignment before cancelling matchmaking.
+type patience struct {
+	dist   string
+	mean   time.Duration
+	stddev time.Duration
+}
+
+func newPatience(dist string, mean, stddev time.Duration) (*patience, error) {
+	switch dist {
+	case "fixed", "uniform", "exponential", "normal":
+	default:
+		return nil, fmt.Errorf("unknown patience distribution: %s", dist)
+	}
+	if mean < 0 || stddev < 0 {
+		return nil, fmt.Errorf("patience must not be negative")
+	}
+	return &patience{dist: dist, mean: mean, stddev: stddev}, nil
+}
+
+// Enabled reports whether players abandon their tickets at all.
+func (p *patience) Enabled() bool {
+	return p.mean > 0
+}
+
+func (p *patience) Sample() time.Duration {
+	var d float64
+	switch p.dist {
+	case "fixed":
+		d = float64(p.mean)
+	case "uniform":
+		d = rand.Float64() * 2 * float64(p.mean)
+	case "exponential":
+		d = rand.ExpFloat64() * float64(p.mean)
+	case "normal":
+		d = rand.NormFloat64()*float64(p.stddev) + float64(p.mean)
+	}
+	if d < 0 {
+		return 0
+	}
+	return time.Duration(d)
+}
+
+func (p *patience) String() string {
+	if !p.Enabled() {
+		return "infinite"
+	}
+	if p.dist == "normal" {
+		return fmt.Sprintf("%s(mean: %s, stddev: %s)", p.dist, p.mean, p.stddev)
+	}
+	return fmt.Sprintf("%s(mean: %s)", p.dist, p.mean)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPatience(t *testing.T) {
+	for _, dist := range []string{"fixed", "uniform", "exponential", "normal"} {
+		_, err := newPatience(dist, time.Minute, time.Second)
+		assert.NoError(t, err, dist)
+	}
+
+	tests := []struct {
+		name   string
+		dist   string
+		mean   time.Duration
+		stddev time.Duration
+	}{
+		{"unknown distribution", "gamma", time.Minute, 0},
+		{"empty distribution", "", time.Minute, 0},
+		{"negative mean", "fixed", -time.Minute, 0},
+		{"negative stddev", "normal", time.Minute, -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newPatience(tt.dist, tt.mean, tt.stddev)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPatienceSample(t *testing.T) {
+	fixed, _ := newPatience("fixed", time.Minute, 0)
+	assert.Equal(t, time.Minute, fixed.Sample())
+
+	uniform, _ := newPatience("uniform", time.Minute, 0)
+	exponential, _ := newPatience("exponential", time.Minute, 0)
+	// The stddev is large enough that most raw samples are negative.
+	normal, _ := newPatience("normal", time.Second, time.Hour)
+	for i := 0; i < 1000; i++ {
+		d := uniform.Sample()
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, 2*time.Minute)
+		assert.GreaterOrEqual(t, exponential.Sample(), time.Duration(0))
+		assert.GreaterOrEqual(t, normal.Sample(), time.Duration(0))
+	}
+
+	disabled, _ := newPatience("fixed", 0, 0)
+	assert.False(t, disabled.Enabled())
+	assert.Equal(t, time.Duration(0), disabled.Sample())
+	assert.Equal(t, "infinite", disabled.String())
+}
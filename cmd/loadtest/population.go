@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	initialSkill = 1500.0
+	skillStddev  = 300.0
+	// skillK is the K-factor of the Elo rating update after each session.
+	skillK = 32.0
+)
+
+type populationConfig struct {
+	Players       int
+	MaxPartySize  int
+	Regions       string
+	SessionLength time.Duration
+	RequeueDelay  time.Duration
+}
+
+func (c *populationConfig) validate() error {
+	if c.Players < 1 {
+		return fmt.Errorf("players must be at least 1")
+	}
+	if c.MaxPartySize < 1 {
+		return fmt.Errorf("max party size must be at least 1")
+	}
+	// A party queues with a single ticket, and the match functions count it as one player regardless of party_size.
+	if c.MaxPartySize > 1 {
+		return fmt.Errorf("max party size must be 1 until the match functions count party_size")
+	}
+	if len(c.regions()) == 0 {
+		return fmt.Errorf("regions are required")
+	}
+	if c.SessionLength < 0 || c.RequeueDelay < 0 {
+		return fmt.Errorf("session length and requeue delay must not be negative")
+	}
+	return nil
+}
+
+func (c *populationConfig) regions() []string {
+	var regions []string
+	for _, r := range strings.Split(c.Regions, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+func (c *populationConfig) String() string {
+	return fmt.Sprintf("players: %d, maxPartySize: %d, regions: %s, session: %s, requeueDelay: %s",
+		c.Players, c.MaxPartySize, c.Regions, c.SessionLength, c.RequeueDelay)
+}
+
+type player struct {
+	ID    string
+	Skill float64
+}
+
+// party is a group of players who queue together with a single ticket.
+type party struct {
+	ID      string
+	Region  string
+	Members []*player
+}
+
+func (p *party) Skill() float64 {
+	var sum float64
+	for _, m := range p.Members {
+		sum += m.Skill
+	}
+	return sum / float64(len(p.Members))
+}
+
+func newParties(c *populationConfig) []*party {
+	regions := c.regions()
+	var parties []*party
+	for i := 0; i < c.Players; {
+		size := rand.Intn(c.MaxPartySize) + 1
+		if size > c.Players-i {
+			size = c.Players - i
+		}
+		pt := &party{
+			ID:     fmt.Sprintf("party-%04d", len(parties)+1),
+			Region: regions[rand.Intn(len(regions))],
+		}
+		for j := 0; j < size; j++ {
+			pt.Members = append(pt.Members, &player{
+				ID:    fmt.Sprintf("player-%04d", i+1),
+				Skill: rand.NormFloat64()*skillStddev + initialSkill,
+			})
+			i++
+		}
+		parties = append(parties, pt)
+	}
+	return parties
+}
+
+// population simulates a closed loop of players who queue, get matched, play a session and re-queue.
+type population struct {
+	*loadtester
+	config  *populationConfig
+	parties []*party
+	stats   *stats
+
+	mu       sync.Mutex
+	queued   int
+	playing  int
+	sessions int
+	// pairings counts the pairs of players matched together, and rematches counts the pairs who had played together before.
+	pairings   int
+	rematches  int
+	playedWith map[[2]string]struct{}
+	// rooms holds the parties playing on each game server (keyed by assignment connection).
+	rooms map[string][]*party
+}
+
+func (lt *loadtester) runPopulation(ctx context.Context, config *populationConfig) {
+	p := &population{
+		loadtester: lt,
+		config:     config,
+		parties:    newParties(config),
+		stats:      &stats{},
+		playedWith: map[[2]string]struct{}{},
+		rooms:      map[string][]*party{},
+	}
+	logger.Info("population mode", "config", config.String(), "parties", len(p.parties))
+	defer func() { logger.Info("result", "population", p.String()) }()
+
+	go p.reportEvery(ctx, func() { logger.Info("stats", "population", p.String()) })
+
+	var wg sync.WaitGroup
+	for _, pt := range p.parties {
+		wg.Add(1)
+		go func(pt *party) {
+			defer wg.Done()
+			p.runParty(ctx, pt)
+		}(pt)
+	}
+	wg.Wait()
+}
+
+func (p *population) runParty(ctx context.Context, pt *party) {
+	for {
+		if assignment := p.queue(ctx, pt); assignment != nil {
+			p.play(ctx, pt, assignment)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.RequeueDelay):
+		}
+	}
+}
+
+func (p *population) queue(ctx context.Context, pt *party) *pb.Assignment {
+	ticket := p.createTicket(ctx, &pb.Ticket{
+		SearchFields: &pb.SearchFields{
+			DoubleArgs: map[string]float64{
+				"skill":      pt.Skill(),
+				"party_size": float64(len(pt.Members)),
+			},
+			StringArgs: map[string]string{
+				"region": pt.Region,
+			},
+		},
+	}, p.stats)
+	if ticket == nil {
+		return nil
+	}
+	logger.Debug("party queued", "party", pt.ID, logging.TicketID(ticket.Id))
+
+	p.mu.Lock()
+	p.queued++
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+	return p.watchTickets(ctx, ticket, p.stats)
+}
+
+func (p *population) play(ctx context.Context, pt *party, assignment *pb.Assignment) {
+	room := assignment.Connection
+	p.joinRoom(pt, room)
+
+	sessionLength := time.Duration(rand.ExpFloat64() * float64(p.config.SessionLength))
+	select {
+	case <-ctx.Done():
+	case <-time.After(sessionLength):
+	}
+	p.leaveRoom(pt, room)
+}
+
+func (p *population) joinRoom(pt *party, room string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, other := range p.rooms[room] {
+		for _, a := range pt.Members {
+			for _, b := range other.Members {
+				key := pairKey(a.ID, b.ID)
+				if _, ok := p.playedWith[key]; ok {
+					p.rematches++
+				}
+				p.playedWith[key] = struct{}{}
+				p.pairings++
+			}
+		}
+	}
+	p.rooms[room] = append(p.rooms[room], pt)
+	p.playing += len(pt.Members)
+}
+
+// leaveRoom ends the session of the party and updates the skill of its members by the simulated result.
+func (p *population) leaveRoom(pt *party, room string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var others []*party
+	for _, other := range p.rooms[room] {
+		if other != pt {
+			others = append(others, other)
+		}
+	}
+	if len(others) > 0 {
+		var opponentSkill float64
+		for _, other := range others {
+			opponentSkill += other.Skill()
+		}
+		opponentSkill /= float64(len(others))
+		expected := 1 / (1 + math.Pow(10, (opponentSkill-pt.Skill())/400))
+		var result float64
+		if rand.Float64() < expected {
+			result = 1
+		}
+		for _, m := range pt.Members {
+			m.Skill += skillK * (result - expected)
+		}
+	}
+
+	if len(others) > 0 {
+		p.rooms[room] = others
+	} else {
+		delete(p.rooms, room)
+	}
+	p.playing -= len(pt.Members)
+	p.sessions++
+}
+
+func (p *population) String() string {
+	// stats has its own lock
+	ticketStats := p.stats.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var sum, sqSum float64
+	for _, pt := range p.parties {
+		for _, m := range pt.Members {
+			sum += m.Skill
+			sqSum += m.Skill * m.Skill
+		}
+	}
+	n := float64(p.config.Players)
+	mean := sum / n
+	stddev := math.Sqrt(math.Max(sqSum/n-mean*mean, 0))
+	var rematchRate float64
+	if p.pairings > 0 {
+		rematchRate = float64(p.rematches) / float64(p.pairings) * 100
+	}
+	return fmt.Sprintf("queued parties: %d, playing players: %d, sessions: %d, rematch: %.1f%%, skill mean: %.1f, stddev: %.1f, %s",
+		p.queued, p.playing, p.sessions, rematchRate, mean, stddev, ticketStats)
+}
+
+func pairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPopulationConfigValidate(t *testing.T) {
+	valid := func() *populationConfig {
+		return &populationConfig{Players: 10, MaxPartySize: 1, Regions: "asia, us", SessionLength: time.Minute, RequeueDelay: time.Second}
+	}
+	assert.NoError(t, valid().validate())
+	assert.Equal(t, []string{"asia", "us"}, valid().regions())
+
+	tests := []struct {
+		name   string
+		modify func(c *populationConfig)
+	}{
+		{"no players", func(c *populationConfig) { c.Players = 0 }},
+		{"no party", func(c *populationConfig) { c.MaxPartySize = 0 }},
+		{"party", func(c *populationConfig) { c.MaxPartySize = 2 }},
+		{"no regions", func(c *populationConfig) { c.Regions = " , " }},
+		{"negative session length", func(c *populationConfig) { c.SessionLength = -time.Second }},
+		{"negative requeue delay", func(c *populationConfig) { c.RequeueDelay = -time.Second }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			assert.Error(t, c.validate())
+		})
+	}
+}
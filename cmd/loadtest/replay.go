@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"github.com/castaneai/openmatch-local-dev/omutils/record"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// runReplay recreates the recorded ticket stream with the same fields and timing.
+func (lt *loadtester) runReplay(ctx context.Context, events []*record.Event) {
+	st := &stats{}
+	defer func() { logger.Info("result", "replay", st.String()) }()
+	go lt.reportEvery(ctx, func() { logger.Info("stats", "replay", st.String()) })
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	replayed := 0
+	for _, e := range events {
+		if e.Type != record.EventTicketCreated {
+			continue
+		}
+		recorded, err := e.GetTicket()
+		if err != nil {
+			logger.Error("failed to read recorded ticket", "error", err)
+			continue
+		}
+		offset, _ := e.Elapsed()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(start.Add(offset))):
+		}
+
+		// Open Match assigns a new ID and CreateTime to the ticket.
+		ticket := lt.createTicket(ctx, &pb.Ticket{
+			SearchFields:    recorded.SearchFields,
+			Extensions:      recorded.Extensions,
+			PersistentField: recorded.PersistentField,
+		}, st)
+		if ticket == nil {
+			continue
+		}
+		replayed++
+		logger.Debug("ticket replayed", "recorded_ticket_id", recorded.Id, logging.TicketID(ticket.Id))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lt.watchTickets(ctx, ticket, st)
+		}()
+	}
+
+	logger.Info("replayed all tickets; waiting for the remaining tickets", "tickets", replayed)
+	waitTickets(ctx, &wg)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// A scenario is a sequence of load phases, e.g.
+//
+//	{"phases": [
+//	  {"name": "warmup", "shape": "ramp", "duration": "1m", "rps": 1, "toRps": 10},
+//	  {"name": "peak", "shape": "spike", "duration": "2m", "rps": 10, "spikeRps": 50, "spikeDuration": "10s", "arrival": "poisson"}
+//	]}
+type scenario struct {
+	Phases []*phase `json:"phases"`
+}
+
+// phase describes the ticket arrival rate over a period of time.
+//
+//   - constant: rps
+//   - ramp: linear from rps to toRps
+//   - step: from rps to toRps in equal steps
+//   - spike: rps, with spikeRps for spikeDuration in the middle of the phase
+//
+// Arrivals are evenly spaced by default, or Poisson-distributed with arrival "poisson".
+type phase struct {
+	Name          string   `json:"name"`
+	Shape         string   `json:"shape"`
+	Arrival       string   `json:"arrival"`
+	Duration      duration `json:"duration"`
+	RPS           float64  `json:"rps"`
+	ToRPS         float64  `json:"toRps"`
+	Steps         int      `json:"steps"`
+	SpikeRPS      float64  `json:"spikeRps"`
+	SpikeDuration duration `json:"spikeDuration"`
+}
+
+const (
+	// idleInterval is how long to wait before re-evaluating the rate when the current rate is zero.
+	idleInterval = 100 * time.Millisecond
+)
+
+func loadScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	var sc scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file: %w", err)
+	}
+	if len(sc.Phases) == 0 {
+		return nil, fmt.Errorf("scenario has no phases")
+	}
+	for i, ph := range sc.Phases {
+		if ph.Name == "" {
+			ph.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if err := ph.validate(); err != nil {
+			return nil, fmt.Errorf("invalid phase '%s': %w", ph.Name, err)
+		}
+	}
+	return &sc, nil
+}
+
+func (p *phase) validate() error {
+	if p.Shape == "" {
+		p.Shape = "constant"
+	}
+	if p.Arrival == "" {
+		p.Arrival = "uniform"
+	}
+	switch p.Shape {
+	case "constant":
+	case "ramp", "step", "spike":
+		if p.Duration <= 0 {
+			return fmt.Errorf("%s shape requires duration", p.Shape)
+		}
+	default:
+		return fmt.Errorf("unknown shape: %s", p.Shape)
+	}
+	switch p.Arrival {
+	case "uniform", "poisson":
+	default:
+		return fmt.Errorf("unknown arrival: %s", p.Arrival)
+	}
+	if p.Shape == "step" && p.Steps <= 0 {
+		return fmt.Errorf("step shape requires steps")
+	}
+	if p.Shape == "spike" && p.SpikeDuration > p.Duration {
+		return fmt.Errorf("spikeDuration must not exceed duration")
+	}
+	if p.RPS < 0 || p.ToRPS < 0 || p.SpikeRPS < 0 {
+		return fmt.Errorf("rps must not be negative")
+	}
+	return nil
+}
+
+// rate returns the target RPS at the elapsed time since the phase started.
+func (p *phase) rate(elapsed time.Duration) float64 {
+	progress := float64(elapsed) / float64(p.Duration)
+	switch p.Shape {
+	case "ramp":
+		return p.RPS + (p.ToRPS-p.RPS)*math.Min(progress, 1)
+	case "step":
+		step := math.Min(math.Floor(progress*float64(p.Steps)), float64(p.Steps-1))
+		if p.Steps == 1 {
+			return p.RPS
+		}
+		return p.RPS + (p.ToRPS-p.RPS)*step/float64(p.Steps-1)
+	case "spike":
+		spikeStart := (p.Duration.Duration() - p.SpikeDuration.Duration()) / 2
+		if elapsed >= spikeStart && elapsed < spikeStart+p.SpikeDuration.Duration() {
+			return p.SpikeRPS
+		}
+		return p.RPS
+	default:
+		return p.RPS
+	}
+}
+
+// nextInterval returns the time until the next ticket arrival.
+func (p *phase) nextInterval(elapsed time.Duration) time.Duration {
+	r := p.rate(elapsed)
+	if r <= 0 {
+		return idleInterval
+	}
+	mean := float64(time.Second) / r
+	if p.Arrival == "poisson" {
+		// inter-arrival times of a Poisson process are exponentially distributed.
+		return time.Duration(rand.ExpFloat64() * mean)
+	}
+	return time.Duration(mean)
+}
+
+func (p *phase) String() string {
+	s := fmt.Sprintf("%s(shape: %s, arrival: %s, rps: %.2f", p.Name, p.Shape, p.Arrival, p.RPS)
+	switch p.Shape {
+	case "ramp":
+		s += fmt.Sprintf(", toRps: %.2f", p.ToRPS)
+	case "step":
+		s += fmt.Sprintf(", toRps: %.2f, steps: %d", p.ToRPS, p.Steps)
+	case "spike":
+		s += fmt.Sprintf(", spikeRps: %.2f, spikeDuration: %s", p.SpikeRPS, p.SpikeDuration)
+	}
+	if p.Duration > 0 {
+		s += fmt.Sprintf(", duration: %s", p.Duration)
+	}
+	return s + ")"
+}
+
+// runScenario creates anonymous one-shot tickets following the load phases of the scenario.
+func (lt *loadtester) runScenario(ctx context.Context, sc *scenario) {
+	for _, ph := range sc.Phases {
+		logger.Info("phase", "name", ph.Name, "phase", ph.String())
+	}
+	var phaseStats []*stats
+	var mu sync.Mutex
+	report := func(prefix string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for i, st := range phaseStats {
+			logger.Info(prefix, "phase", sc.Phases[i].Name, "stats", st.String())
+		}
+	}
+	defer report("result")
+	go lt.reportEvery(ctx, func() { report("stats") })
+
+	var wg sync.WaitGroup
+	for _, ph := range sc.Phases {
+		st := &stats{}
+		mu.Lock()
+		phaseStats = append(phaseStats, st)
+		mu.Unlock()
+		logger.Info("phase started", "phase", ph.Name)
+		runPhase(ctx, ph, func() {
+			ticket := lt.createTicket(ctx, &pb.Ticket{}, st)
+			if ticket == nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lt.watchTickets(ctx, ticket, st)
+			}()
+		})
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	logger.Info("all phases finished; waiting for the remaining tickets")
+	waitTickets(ctx, &wg)
+}
+
+// runPhase calls createTicket at the arrival rate of the phase until the phase ends.
+func runPhase(ctx context.Context, ph *phase, createTicket func()) {
+	if ph.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ph.Duration.Duration())
+		defer cancel()
+	}
+	start := time.Now()
+	timer := time.NewTimer(ph.nextInterval(0))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			elapsed := time.Since(start)
+			if ph.rate(elapsed) > 0 {
+				createTicket()
+			}
+			timer.Reset(ph.nextInterval(elapsed))
+		}
+	}
+}
+
+// duration is a time.Duration that is written as a string like "30s" in JSON.
+type duration time.Duration
+
+func (d duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		phase   *phase
+		elapsed time.Duration
+		want    float64
+	}{
+		{"constant", &phase{Shape: "constant", RPS: 5}, 0, 5},
+		{"constant without duration", &phase{Shape: "constant", RPS: 5}, time.Hour, 5},
+		{"ramp start", &phase{Shape: "ramp", Duration: duration(10 * time.Second), RPS: 2, ToRPS: 12}, 0, 2},
+		{"ramp middle", &phase{Shape: "ramp", Duration: duration(10 * time.Second), RPS: 2, ToRPS: 12}, 5 * time.Second, 7},
+		{"ramp end", &phase{Shape: "ramp", Duration: duration(10 * time.Second), RPS: 2, ToRPS: 12}, 10 * time.Second, 12},
+		{"ramp after end", &phase{Shape: "ramp", Duration: duration(10 * time.Second), RPS: 2, ToRPS: 12}, 15 * time.Second, 12},
+		{"ramp down", &phase{Shape: "ramp", Duration: duration(10 * time.Second), RPS: 12, ToRPS: 2}, 5 * time.Second, 7},
+		{"step start", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 0, 1},
+		{"step before boundary", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 2500*time.Millisecond - 1, 1},
+		{"step at boundary", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 2500 * time.Millisecond, 2},
+		{"step middle", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 5 * time.Second, 3},
+		{"step last", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 7500 * time.Millisecond, 4},
+		{"step end", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 1, ToRPS: 4, Steps: 4}, 10 * time.Second, 4},
+		{"single step start", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 3, ToRPS: 9, Steps: 1}, 0, 3},
+		{"single step end", &phase{Shape: "step", Duration: duration(10 * time.Second), RPS: 3, ToRPS: 9, Steps: 1}, 10 * time.Second, 3},
+		{"spike start", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 0, 5},
+		{"spike before", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 4*time.Second - 1, 5},
+		{"spike begins", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 4 * time.Second, 50},
+		{"spike middle", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 5 * time.Second, 50},
+		{"spike ends", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 6 * time.Second, 5},
+		{"spike end", &phase{Shape: "spike", Duration: duration(10 * time.Second), RPS: 5, SpikeRPS: 50, SpikeDuration: duration(2 * time.Second)}, 10 * time.Second, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.phase.rate(tt.elapsed), 1e-9)
+		})
+	}
+}
+
+func TestPhaseNextInterval(t *testing.T) {
+	uniform := &phase{Shape: "constant", Arrival: "uniform", RPS: 4}
+	assert.Equal(t, 250*time.Millisecond, uniform.nextInterval(0))
+
+	idle := &phase{Shape: "constant", Arrival: "uniform", RPS: 0}
+	assert.Equal(t, idleInterval, idle.nextInterval(0))
+
+	poisson := &phase{Shape: "constant", Arrival: "poisson", RPS: 4}
+	const n = 10000
+	var sum time.Duration
+	for i := 0; i < n; i++ {
+		interval := poisson.nextInterval(0)
+		assert.GreaterOrEqual(t, interval, time.Duration(0))
+		sum += interval
+	}
+	assert.InDelta(t, float64(250*time.Millisecond), float64(sum/n), float64(25*time.Millisecond))
+}
+
+func TestPhaseValidate(t *testing.T) {
+	p := &phase{RPS: 1}
+	if assert.NoError(t, p.validate()) {
+		assert.Equal(t, "constant", p.Shape)
+		assert.Equal(t, "uniform", p.Arrival)
+	}
+
+	tests := []struct {
+		name  string
+		phase *phase
+		want  string
+	}{
+		{"ramp without duration", &phase{Shape: "ramp", RPS: 1, ToRPS: 2}, "ramp shape requires duration"},
+		{"step without duration", &phase{Shape: "step", RPS: 1, ToRPS: 2, Steps: 2}, "step shape requires duration"},
+		{"spike without duration", &phase{Shape: "spike", RPS: 1, SpikeRPS: 2}, "spike shape requires duration"},
+		{"unknown shape", &phase{Shape: "sine", RPS: 1}, "unknown shape: sine"},
+		{"unknown arrival", &phase{Arrival: "burst", RPS: 1}, "unknown arrival: burst"},
+		{"step without steps", &phase{Shape: "step", Duration: duration(time.Minute), RPS: 1, ToRPS: 2}, "step shape requires steps"},
+		{"spike longer than phase", &phase{Shape: "spike", Duration: duration(time.Second), RPS: 1, SpikeRPS: 2, SpikeDuration: duration(time.Minute)}, "spikeDuration must not exceed duration"},
+		{"negative rps", &phase{RPS: -1}, "rps must not be negative"},
+		{"negative toRps", &phase{Shape: "ramp", Duration: duration(time.Minute), RPS: 1, ToRPS: -1}, "rps must not be negative"},
+		{"negative spikeRps", &phase{Shape: "spike", Duration: duration(time.Minute), RPS: 1, SpikeRPS: -1}, "rps must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.phase.validate(), tt.want)
+		})
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	write := func(t *testing.T, data string) string {
+		path := filepath.Join(t.TempDir(), "scenario.json")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	sc, err := loadScenario(write(t, `{"phases": [
+		{"name": "warmup", "shape": "ramp", "duration": "1m", "rps": 1, "toRps": 10},
+		{"shape": "spike", "duration": "2m30s", "rps": 10, "spikeRps": 50, "spikeDuration": "10s", "arrival": "poisson"}
+	]}`))
+	if assert.NoError(t, err) && assert.Len(t, sc.Phases, 2) {
+		assert.Equal(t, "warmup", sc.Phases[0].Name)
+		assert.Equal(t, time.Minute, sc.Phases[0].Duration.Duration())
+		assert.Equal(t, "phase-2", sc.Phases[1].Name)
+		assert.Equal(t, 150*time.Second, sc.Phases[1].Duration.Duration())
+		assert.Equal(t, 10*time.Second, sc.Phases[1].SpikeDuration.Duration())
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"no phases", `{"phases": []}`, "scenario has no phases"},
+		{"invalid duration", `{"phases": [{"duration": "1 minute"}]}`, "failed to parse scenario file"},
+		{"numeric duration", `{"phases": [{"duration": 60}]}`, "failed to parse scenario file"},
+		{"invalid phase", `{"phases": [{"rps": 1}, {"shape": "ramp", "rps": 1}]}`, "invalid phase 'phase-2': ramp shape requires duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadScenario(write(t, tt.data))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	_, err = loadScenario(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read scenario file")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/quality"
+)
+
+// stats collects the outcome of each ticket created by the load test.
+type stats struct {
+	mu        sync.Mutex
+	created   int
+	failed    int
+	assigned  int
+	abandoned int
+	latencies []time.Duration
+}
+
+func (s *stats) TicketCreated() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created++
+}
+
+func (s *stats) TicketFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed++
+}
+
+func (s *stats) TicketAssigned(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assigned++
+	s.latencies = append(s.latencies, latency)
+}
+
+func (s *stats) TicketAbandoned() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.abandoned++
+}
+
+func (s *stats) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latencies := make([]time.Duration, len(s.latencies))
+	copy(latencies, s.latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return fmt.Sprintf("created: %d, failed: %d, assigned: %d, abandoned: %d (%.1f%%), latency p50: %s, p90: %s, p99: %s, max: %s",
+		s.created, s.failed, s.assigned, s.abandoned, s.abandonRate(),
+		quality.Percentile(latencies, 50), quality.Percentile(latencies, 90), quality.Percentile(latencies, 99), quality.Percentile(latencies, 100))
+}
+
+// abandonRate returns the percentage of the abandoned tickets over the tickets whose outcome is already known.
+// s.mu must be held.
+func (s *stats) abandonRate() float64 {
+	finished := s.assigned + s.abandoned
+	if finished == 0 {
+		return 0
+	}
+	return float64(s.abandoned) / float64(finished) * 100
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsAbandonRate(t *testing.T) {
+	s := &stats{}
+	assert.Equal(t, 0.0, s.abandonRate())
+
+	s.TicketCreated()
+	s.TicketCreated()
+	s.TicketCreated()
+	s.TicketCreated()
+	s.TicketAssigned(time.Second)
+	s.TicketAssigned(3 * time.Second)
+	s.TicketAbandoned()
+	// The pending and failed tickets are not counted.
+	s.TicketFailed()
+	assert.InDelta(t, 100.0/3, s.abandonRate(), 1e-9)
+	assert.Contains(t, s.String(), "abandoned: 1 (33.3%)")
+
+	s = &stats{}
+	s.TicketAbandoned()
+	assert.Equal(t, 100.0, s.abandonRate())
+}
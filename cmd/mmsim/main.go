@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var logger = logging.Component("simulator")
+
+var matchProfile = &pb.MatchProfile{
+	Name: "test-profile",
+	Pools: []*pb.Pool{
+		{Name: "test-pool"},
+	},
+}
+
+type matchFunctionSpec struct {
+	makeMatches mmlogic.MakeMatchesFunc
+	// capacity is the number of players in a game server.
+	capacity int
+}
+
+var matchFunctions = map[string]matchFunctionSpec{
+	"simple1vs1": {makeMatches: mmlogic.Simple1vs1, capacity: mmlogic.Simple1vs1PlayersPerMatch},
+	"backfill3":  {makeMatches: mmlogic.Backfill3, capacity: omutils.PlayersPerMatch},
+}
+
+func main() {
+	var matchFunction, arrivalDist, ticketsFile string
+	var rps float64
+	var duration, interval time.Duration
+	var seed int64
+	flag.StringVar(&matchFunction, "matchfunction", "backfill3", "A name of Match Function (simple1vs1 or backfill3)")
+	flag.StringVar(&ticketsFile, "tickets", "", "A path to record file of ticket traffic (see loadtest -record); synthetic tickets are generated if empty")
+	flag.Float64Var(&rps, "rps", 1.0, "RPS of synthetic tickets")
+	flag.DurationVar(&duration, "duration", 1*time.Hour, "A virtual duration of synthetic tickets")
+	flag.StringVar(&arrivalDist, "arrival", "poisson", "A distribution of synthetic ticket arrivals (uniform or poisson)")
+	flag.Int64Var(&seed, "seed", 1, "A random seed of synthetic tickets")
+	flag.DurationVar(&interval, "interval", 1*time.Second, "A virtual interval of the director calling FetchMatches")
+	flag.Parse()
+	logging.SetupFromEnv()
+
+	mf, ok := matchFunctions[matchFunction]
+	if !ok {
+		logging.Fatal(logger, "unknown match function", "matchfunction", matchFunction)
+	}
+	if interval <= 0 {
+		logging.Fatal(logger, "interval must be positive")
+	}
+
+	var arrivals []*arrival
+	if ticketsFile != "" {
+		as, err := recordedArrivals(ticketsFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to load tickets", "error", err)
+		}
+		arrivals = as
+	} else {
+		if rps <= 0 {
+			logging.Fatal(logger, "rps must be positive")
+		}
+		if arrivalDist != "uniform" && arrivalDist != "poisson" {
+			logging.Fatal(logger, "unknown arrival", "arrival", arrivalDist)
+		}
+		arrivals = syntheticArrivals(rand.New(rand.NewSource(seed)), rps, duration, arrivalDist == "poisson")
+	}
+	logger.Info("simulating", "tickets", len(arrivals), "matchfunction", matchFunction, "interval", interval)
+
+	start := time.Now()
+	res, err := newSimulator(matchProfile, mf.makeMatches, mf.capacity, interval).Run(arrivals)
+	if err != nil {
+		logging.Fatal(logger, "failed to simulate", "error", err)
+	}
+	logger.Info("simulated", "virtual_time", res.VirtualTime, "elapsed", time.Since(start).Round(time.Millisecond))
+	fmt.Print(res)
+}
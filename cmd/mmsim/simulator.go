@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"github.com/castaneai/openmatch-local-dev/omutils/quality"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// simulator runs a match function's matching logic against a ticket stream on a virtual clock,
+// in the same way as a director calling FetchMatches at a fixed interval.
+type simulator struct {
+	profile     *pb.MatchProfile
+	makeMatches mmlogic.MakeMatchesFunc
+	capacity    int
+	interval    time.Duration
+
+	now       time.Time
+	tickets   []*pb.Ticket
+	arrivedAt map[string]time.Time
+	// backfills carry over between runs of the match function, as Open Match keeps them between FetchMatches calls.
+	backfills      []*pb.Backfill
+	nextBackfillID int
+
+	result *result
+}
+
+type result struct {
+	VirtualTime     time.Duration
+	Tickets         int
+	Matches         int
+	NewGameServers  int
+	BackfillMatches int
+	MatchedTickets  int
+	Unmatched       int
+	Waits           []time.Duration
+	Quality         *quality.Summary
+}
+
+func newSimulator(profile *pb.MatchProfile, makeMatches mmlogic.MakeMatchesFunc, capacity int, interval time.Duration) *simulator {
+	return &simulator{
+		profile:     profile,
+		makeMatches: makeMatches,
+		capacity:    capacity,
+		interval:    interval,
+		now:         time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		arrivedAt:   map[string]time.Time{},
+		result:      &result{Quality: &quality.Summary{}},
+	}
+}
+
+func (s *simulator) Run(arrivals []*arrival) (*result, error) {
+	sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].Offset < arrivals[j].Offset })
+	start := s.now
+	for {
+		for len(arrivals) > 0 && !start.Add(arrivals[0].Offset).After(s.now) {
+			s.arrive(arrivals[0].Ticket, start.Add(arrivals[0].Offset))
+			arrivals = arrivals[1:]
+		}
+		matched, err := s.runMatchFunction()
+		if err != nil {
+			return nil, fmt.Errorf("failed to make matches at %s: %w", s.now.Sub(start), err)
+		}
+		// Stop when no more tickets will arrive and the match function makes no progress.
+		if len(arrivals) == 0 && matched == 0 {
+			break
+		}
+		s.now = s.now.Add(s.interval)
+	}
+	s.result.VirtualTime = s.now.Sub(start)
+	s.result.Unmatched = len(s.tickets)
+	return s.result, nil
+}
+
+func (s *simulator) arrive(ticket *pb.Ticket, at time.Time) {
+	ticket.CreateTime = timestamppb.New(at)
+	s.tickets = append(s.tickets, ticket)
+	s.arrivedAt[ticket.Id] = at
+	s.result.Tickets++
+}
+
+// runMatchFunction runs the match function once and applies its proposals, returning the number of matched tickets.
+func (s *simulator) runMatchFunction() (int, error) {
+	poolName := s.profile.Pools[0].Name
+	matches, err := s.makeMatches(s.profile,
+		map[string][]*pb.Ticket{poolName: s.tickets},
+		map[string][]*pb.Backfill{poolName: s.backfills})
+	if err != nil {
+		return 0, err
+	}
+	if err := mmlogic.ValidateProposals(s.profile, map[string][]*pb.Ticket{poolName: s.tickets}, map[string][]*pb.Backfill{poolName: s.backfills}, matches, s.capacity); err != nil {
+		return 0, fmt.Errorf("invalid proposals at %s: %w", s.now, err)
+	}
+
+	matched := map[string]struct{}{}
+	for _, match := range matches {
+		s.result.Quality.Add(quality.Evaluate(match, s.capacity, s.now))
+		for _, ticket := range match.Tickets {
+			matched[ticket.Id] = struct{}{}
+			s.result.Waits = append(s.result.Waits, s.now.Sub(s.arrivedAt[ticket.Id]))
+			delete(s.arrivedAt, ticket.Id)
+		}
+		s.result.Matches++
+		s.result.MatchedTickets += len(match.Tickets)
+		if match.AllocateGameserver {
+			s.result.NewGameServers++
+		} else {
+			s.result.BackfillMatches++
+		}
+		if match.Backfill != nil && match.AllocateGameserver {
+			// The director creates the new backfill and the game server starts acknowledging it.
+			s.nextBackfillID++
+			match.Backfill.Id = fmt.Sprintf("backfill-%d", s.nextBackfillID)
+			match.Backfill.Generation = 1
+			s.backfills = append(s.backfills, match.Backfill)
+		}
+		var tids []string
+		for _, ticket := range match.Tickets {
+			tids = append(tids, ticket.Id)
+		}
+		logger.Debug("match", "virtual_time", s.now, logging.Profile(s.profile.Name), logging.MatchID(match.MatchId),
+			logging.TicketIDs(tids), logging.BackfillID(match.Backfill.GetId()), "allocate_gameserver", match.AllocateGameserver)
+	}
+
+	var remaining []*pb.Ticket
+	for _, ticket := range s.tickets {
+		if _, ok := matched[ticket.Id]; !ok {
+			remaining = append(remaining, ticket)
+		}
+	}
+	s.tickets = remaining
+
+	// Full backfills no longer take tickets, so they are dropped as the game server would stop backfilling.
+	var backfills []*pb.Backfill
+	for _, backfill := range s.backfills {
+		openSlots, err := omutils.GetOpenSlots(backfill)
+		if err != nil {
+			return 0, err
+		}
+		if openSlots > 0 {
+			backfills = append(backfills, backfill)
+		}
+	}
+	s.backfills = backfills
+	return len(matched), nil
+}
+
+func (r *result) String() string {
+	waits := make([]time.Duration, len(r.Waits))
+	copy(waits, r.Waits)
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	var avgTickets float64
+	if r.Matches > 0 {
+		avgTickets = float64(r.MatchedTickets) / float64(r.Matches)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "virtual time: %s\n", r.VirtualTime)
+	fmt.Fprintf(&sb, "tickets: %d (matched: %d, unmatched: %d)\n", r.Tickets, r.MatchedTickets, r.Unmatched)
+	fmt.Fprintf(&sb, "matches: %d (new game servers: %d, backfill: %d, avg tickets per match: %.2f)\n",
+		r.Matches, r.NewGameServers, r.BackfillMatches, avgTickets)
+	fmt.Fprintf(&sb, "wait time p50: %s, p90: %s, p99: %s, max: %s\n",
+		quality.Percentile(waits, 50), quality.Percentile(waits, 90), quality.Percentile(waits, 99), quality.Percentile(waits, 100))
+	fmt.Fprintf(&sb, "quality: %s\n", r.Quality)
+	return sb.String()
+}
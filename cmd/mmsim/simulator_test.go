@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestSimulatorCarriesOverBackfills(t *testing.T) {
+	arrivals := []*arrival{
+		{Offset: 0, Ticket: &pb.Ticket{Id: "ticket-1"}},
+		{Offset: 1500 * time.Millisecond, Ticket: &pb.Ticket{Id: "ticket-2"}},
+		{Offset: 3500 * time.Millisecond, Ticket: &pb.Ticket{Id: "ticket-3"}},
+	}
+	res, err := newSimulator(matchProfile, mmlogic.Backfill3, omutils.PlayersPerMatch, time.Second).Run(arrivals)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.MatchedTickets)
+	assert.Equal(t, 0, res.Unmatched)
+	// ticket-1 allocates a game server, and the others join it through the backfill.
+	assert.Equal(t, 1, res.NewGameServers)
+	assert.Equal(t, 2, res.BackfillMatches)
+	assert.Equal(t, []time.Duration{0, 500 * time.Millisecond, 500 * time.Millisecond}, res.Waits)
+}
+
+func TestSimulatorLeavesUnmatchedTickets(t *testing.T) {
+	arrivals := []*arrival{
+		{Offset: 0, Ticket: &pb.Ticket{Id: "ticket-1"}},
+		{Offset: 0, Ticket: &pb.Ticket{Id: "ticket-2"}},
+		{Offset: 10 * time.Second, Ticket: &pb.Ticket{Id: "ticket-3"}},
+	}
+	res, err := newSimulator(matchProfile, mmlogic.Simple1vs1, mmlogic.Simple1vs1PlayersPerMatch, time.Second).Run(arrivals)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Matches)
+	assert.Equal(t, 1, res.Unmatched)
+	assert.Equal(t, 10*time.Second, res.VirtualTime)
+}
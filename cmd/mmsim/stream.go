@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/record"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var regions = []string{"asia", "us", "eu"}
+
+// arrival is a ticket that arrives at the offset from the start of the simulation.
+type arrival struct {
+	Offset time.Duration
+	Ticket *pb.Ticket
+}
+
+// syntheticArrivals generates tickets with random skill and region at the given rate.
+func syntheticArrivals(rng *rand.Rand, rps float64, duration time.Duration, poisson bool) []*arrival {
+	var arrivals []*arrival
+	mean := float64(time.Second) / rps
+	for offset := time.Duration(0); ; {
+		if poisson {
+			offset += time.Duration(rng.ExpFloat64() * mean)
+		} else {
+			offset += time.Duration(mean)
+		}
+		if offset >= duration {
+			break
+		}
+		arrivals = append(arrivals, &arrival{
+			Offset: offset,
+			Ticket: &pb.Ticket{
+				Id: fmt.Sprintf("ticket-%d", len(arrivals)+1),
+				SearchFields: &pb.SearchFields{
+					DoubleArgs: map[string]float64{"skill": rng.NormFloat64()*300 + 1500},
+					StringArgs: map[string]string{"region": regions[rng.Intn(len(regions))]},
+				},
+			},
+		})
+	}
+	return arrivals
+}
+
+// recordedArrivals reads the ticket stream from a record file (see omutils/record).
+// Lines without type are treated as ticket creations, so that a ticket stream can be written by hand.
+func recordedArrivals(path string) ([]*arrival, error) {
+	events, err := record.ReadEvents(path)
+	if err != nil {
+		return nil, err
+	}
+	var arrivals []*arrival
+	for i, e := range events {
+		if e.Type != record.EventTicketCreated && e.Type != "" {
+			continue
+		}
+		offset, err := e.Elapsed()
+		if err != nil {
+			return nil, err
+		}
+		ticket, err := e.GetTicket()
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket at event %d: %w", i+1, err)
+		}
+		if ticket.Id == "" {
+			ticket.Id = fmt.Sprintf("ticket-%d", i+1)
+		}
+		arrivals = append(arrivals, &arrival{Offset: offset, Ticket: ticket})
+	}
+	return arrivals, nil
+}
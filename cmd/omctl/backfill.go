@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func getBackfill(ctx context.Context, c *omctl, args []string) error {
+	id, err := parseID("backfill get", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	backfill, err := fe.GetBackfill(ctx, &pb.GetBackfillRequest{BackfillId: id})
+	if err != nil {
+		return fmt.Errorf("failed to get backfill: %w", err)
+	}
+	return c.printer.Backfills([]*pb.Backfill{backfill})
+}
+
+func deleteBackfill(ctx context.Context, c *omctl, args []string) error {
+	id, err := parseID("backfill delete", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	if _, err := fe.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: id}); err != nil {
+		return fmt.Errorf("failed to delete backfill: %w", err)
+	}
+	return c.printer.Deleted("backfill", id)
+}
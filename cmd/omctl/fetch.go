@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/matchfunction"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+type fetchFlags struct {
+	profileName string
+	mfHost      string
+	mfPort      int
+	pool        poolFlags
+}
+
+func (f *fetchFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.profileName, "profile", "test-profile", "A name of the match profile")
+	fs.StringVar(&f.mfHost, "matchfunction", "matchfunction-simple1vs1.open-match.svc.cluster.local.", "A host of the match function")
+	fs.IntVar(&f.mfPort, "matchfunction-port", 50502, "A port of the match function")
+	f.pool.register(fs)
+}
+
+func (f *fetchFlags) request() (*pb.FetchMatchesRequest, error) {
+	pool, err := f.pool.pool()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FetchMatchesRequest{
+		Config: &pb.FunctionConfig{
+			Host: f.mfHost,
+			Port: int32(f.mfPort),
+			Type: pb.FunctionConfig_GRPC,
+		},
+		Profile: &pb.MatchProfile{Name: f.profileName, Pools: []*pb.Pool{pool}},
+	}, nil
+}
+
+// fetchMatches runs FetchMatches once for the profile and prints the proposals.
+// The tickets in the proposals stay pending until Open Match's pending timeout expires.
+func fetchMatches(ctx context.Context, c *omctl, args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	var ff fetchFlags
+	ff.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	req, err := ff.request()
+	if err != nil {
+		return err
+	}
+	be, err := c.backend()
+	if err != nil {
+		return err
+	}
+	matches, err := fetch(ctx, be, req)
+	if err != nil {
+		return err
+	}
+	return c.printer.Matches(matches)
+}
+
+// dryRun runs FetchMatches once for the profile and prints the proposals,
+// then releases the tickets and reverts the backfills so that the pool is left intact.
+func dryRun(ctx context.Context, c *omctl, args []string) error {
+	fs := flag.NewFlagSet("dryrun", flag.ContinueOnError)
+	var ff fetchFlags
+	ff.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	req, err := ff.request()
+	if err != nil {
+		return err
+	}
+	be, err := c.backend()
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	qs, err := c.query()
+	if err != nil {
+		return err
+	}
+
+	// FetchMatches updates the existing backfills in the proposals, so keep them to revert.
+	before := map[string]*pb.Backfill{}
+	for _, pool := range req.Profile.Pools {
+		backfills, err := matchfunction.QueryBackfillPool(ctx, qs, pool)
+		if err != nil {
+			return fmt.Errorf("failed to query backfills: %w", err)
+		}
+		for _, b := range backfills {
+			before[b.Id] = b
+		}
+	}
+
+	matches, err := fetch(ctx, be, req)
+	if err != nil {
+		return err
+	}
+	// Revert even if interrupted while printing.
+	defer func() {
+		if err := revertProposals(context.Background(), be, fe, matches, before); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to revert proposals: %+v\n", err)
+		}
+	}()
+	return c.printer.Matches(matches)
+}
+
+func revertProposals(ctx context.Context, be pb.BackendServiceClient, fe pb.FrontendServiceClient, matches []*pb.Match, before map[string]*pb.Backfill) error {
+	var ticketIDs []string
+	for _, match := range matches {
+		for _, ticket := range match.Tickets {
+			ticketIDs = append(ticketIDs, ticket.Id)
+		}
+	}
+	if len(ticketIDs) > 0 {
+		if _, err := be.ReleaseTickets(ctx, &pb.ReleaseTicketsRequest{TicketIds: ticketIDs}); err != nil {
+			return fmt.Errorf("failed to release tickets: %w", err)
+		}
+	}
+	for _, match := range matches {
+		if match.Backfill == nil || match.Backfill.Id == "" {
+			continue
+		}
+		if orig, ok := before[match.Backfill.Id]; ok {
+			// Restore openSlots of the existing backfill.
+			openSlots, err := omutils.GetOpenSlots(orig)
+			if err != nil {
+				return err
+			}
+			current, err := fe.GetBackfill(ctx, &pb.GetBackfillRequest{BackfillId: orig.Id})
+			if err != nil {
+				return fmt.Errorf("failed to get backfill: %w", err)
+			}
+			if err := omutils.SetOpenSlots(current, openSlots); err != nil {
+				return err
+			}
+			if _, err := fe.UpdateBackfill(ctx, &pb.UpdateBackfillRequest{Backfill: current}); err != nil {
+				return fmt.Errorf("failed to update backfill: %w", err)
+			}
+		} else if match.AllocateGameserver {
+			// A new backfill created by the proposal; no game server will acknowledge it.
+			if _, err := fe.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: match.Backfill.Id}); err != nil {
+				return fmt.Errorf("failed to delete backfill: %w", err)
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "released %d ticket(s) of %d proposal(s)\n", len(ticketIDs), len(matches))
+	return nil
+}
+
+func fetch(ctx context.Context, be pb.BackendServiceClient, req *pb.FetchMatchesRequest) ([]*pb.Match, error) {
+	stream, err := be.FetchMatches(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch matches: %w", err)
+	}
+	var matches []*pb.Match
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to recv matches: %w", err)
+		}
+		matches = append(matches, resp.Match)
+	}
+	return matches, nil
+}
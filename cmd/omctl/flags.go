@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// stringList is a flag that can be specified multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func splitKeyValue(s string) (string, string, error) {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return "", "", fmt.Errorf("expected key=value: %s", s)
+	}
+	return k, v, nil
+}
+
+type searchFieldsFlags struct {
+	strings stringList
+	doubles stringList
+	tags    stringList
+}
+
+func (f *searchFieldsFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.strings, "string", "A string arg as key=value (repeatable)")
+	fs.Var(&f.doubles, "double", "A double arg as key=value (repeatable)")
+	fs.Var(&f.tags, "tag", "A tag (repeatable)")
+}
+
+func (f *searchFieldsFlags) searchFields() (*pb.SearchFields, error) {
+	sf := &pb.SearchFields{
+		StringArgs: map[string]string{},
+		DoubleArgs: map[string]float64{},
+		Tags:       f.tags,
+	}
+	for _, s := range f.strings {
+		k, v, err := splitKeyValue(s)
+		if err != nil {
+			return nil, err
+		}
+		sf.StringArgs[k] = v
+	}
+	for _, s := range f.doubles {
+		k, v, err := splitKeyValue(s)
+		if err != nil {
+			return nil, err
+		}
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double arg %s: %w", s, err)
+		}
+		sf.DoubleArgs[k] = d
+	}
+	return sf, nil
+}
+
+type poolFlags struct {
+	name    string
+	strings stringList
+	ranges  stringList
+	tags    stringList
+}
+
+func (f *poolFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.name, "pool", "test-pool", "A name of the pool")
+	fs.Var(&f.strings, "string", "A string equals filter as key=value (repeatable)")
+	fs.Var(&f.ranges, "range", "A double range filter as key=min:max (repeatable)")
+	fs.Var(&f.tags, "tag", "A tag present filter (repeatable)")
+}
+
+func (f *poolFlags) pool() (*pb.Pool, error) {
+	pool := &pb.Pool{Name: f.name}
+	for _, s := range f.strings {
+		k, v, err := splitKeyValue(s)
+		if err != nil {
+			return nil, err
+		}
+		pool.StringEqualsFilters = append(pool.StringEqualsFilters, &pb.StringEqualsFilter{StringArg: k, Value: v})
+	}
+	for _, s := range f.ranges {
+		k, v, err := splitKeyValue(s)
+		if err != nil {
+			return nil, err
+		}
+		minStr, maxStr, ok := strings.Cut(v, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected key=min:max: %s", s)
+		}
+		min, err := strconv.ParseFloat(minStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %w", s, err)
+		}
+		max, err := strconv.ParseFloat(maxStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %w", s, err)
+		}
+		pool.DoubleRangeFilters = append(pool.DoubleRangeFilters, &pb.DoubleRangeFilter{DoubleArg: k, Min: min, Max: max})
+	}
+	for _, tag := range f.tags {
+		pool.TagPresentFilters = append(pool.TagPresentFilters, &pb.TagPresentFilter{Tag: tag})
+	}
+	return pool, nil
+}
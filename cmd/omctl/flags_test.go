@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var pf poolFlags
+	pf.register(fs)
+	assert.NoError(t, fs.Parse([]string{"-pool", "p1", "-string", "region=asia", "-range", "skill=1000:2000", "-tag", "beginner"}))
+
+	pool, err := pf.pool()
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", pool.Name)
+	assert.Len(t, pool.StringEqualsFilters, 1)
+	assert.Equal(t, "region", pool.StringEqualsFilters[0].StringArg)
+	assert.Equal(t, "asia", pool.StringEqualsFilters[0].Value)
+	assert.Len(t, pool.DoubleRangeFilters, 1)
+	assert.Equal(t, "skill", pool.DoubleRangeFilters[0].DoubleArg)
+	assert.Equal(t, 1000.0, pool.DoubleRangeFilters[0].Min)
+	assert.Equal(t, 2000.0, pool.DoubleRangeFilters[0].Max)
+	assert.Len(t, pool.TagPresentFilters, 1)
+	assert.Equal(t, "beginner", pool.TagPresentFilters[0].Tag)
+
+	pf.ranges = stringList{"skill=1000"}
+	_, err = pf.pool()
+	assert.Error(t, err)
+}
+
+func TestSearchFieldsFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var sf searchFieldsFlags
+	sf.register(fs)
+	assert.NoError(t, fs.Parse([]string{"-string", "region=eu", "-double", "skill=1500", "-tag", "ranked"}))
+
+	searchFields, err := sf.searchFields()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu"}, searchFields.StringArgs)
+	assert.Equal(t, map[string]float64{"skill": 1500}, searchFields.DoubleArgs)
+	assert.Equal(t, []string{"ranked"}, searchFields.Tags)
+
+	sf.doubles = stringList{"skill=high"}
+	_, err = sf.searchFields()
+	assert.Error(t, err)
+}
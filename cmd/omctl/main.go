@@ -0,0 +1,159 @@
+// omctl inspects and manages the state of Open Match.
+//
+//	omctl [global flags] <command> [flags] [args]
+//
+// Commands:
+//
+//	ticket create [-string key=value] [-double key=value] [-tag tag]
+//	ticket get <ticket-id>
+//	ticket delete <ticket-id>
+//	ticket watch <ticket-id>
+//	pool tickets [pool filter flags]
+//	pool backfills [pool filter flags]
+//	pool cleanup <pool filter flags>
+//	backfill get <backfill-id>
+//	backfill delete <backfill-id>
+//	fetch [-profile name] [-matchfunction host] [pool filter flags]
+//	dryrun [-profile name] [-matchfunction host] [pool filter flags]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+type omctl struct {
+	frontendAddr string
+	backendAddr  string
+	queryAddr    string
+	tls          omutils.TLSConfig
+	printer      *printer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *omctl, args []string) error
+}
+
+var commands = map[string]map[string]command{
+	"ticket": {
+		"create": {usage: "[-string key=value] [-double key=value] [-tag tag]", run: createTicket},
+		"get":    {usage: "<ticket-id>", run: getTicket},
+		"delete": {usage: "<ticket-id>", run: deleteTicket},
+		"watch":  {usage: "<ticket-id>", run: watchAssignments},
+	},
+	"pool": {
+		"tickets":   {usage: "[pool filter flags]", run: queryTickets},
+		"backfills": {usage: "[pool filter flags]", run: queryBackfills},
+		"cleanup":   {usage: "<pool filter flags> (at least one filter is required)", run: cleanupPool},
+	},
+	"backfill": {
+		"get":    {usage: "<backfill-id>", run: getBackfill},
+		"delete": {usage: "<backfill-id>", run: deleteBackfill},
+	},
+	"fetch": {
+		"": {usage: "[-profile name] [-matchfunction host] [pool filter flags]", run: fetchMatches},
+	},
+	"dryrun": {
+		"": {usage: "[-profile name] [-matchfunction host] [pool filter flags] (releases the tickets after printing)", run: dryRun},
+	},
+}
+
+func main() {
+	c := &omctl{}
+	var output string
+	flag.StringVar(&c.frontendAddr, "frontend", "localhost:50504", "An address of Open Match frontend")
+	flag.StringVar(&c.backendAddr, "backend", "localhost:50505", "An address of Open Match backend")
+	flag.StringVar(&c.queryAddr, "query", "localhost:50503", "An address of Open Match query")
+	c.tls.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&output, "o", "table", "An output format (table or json)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if output != "table" && output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format: %s\n", output)
+		os.Exit(2)
+	}
+	c.printer = &printer{w: os.Stdout, json: output == "json"}
+
+	cmd, args, ok := lookupCommand(flag.Args())
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := cmd.run(ctx, c, args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %+v\n", err)
+		os.Exit(1)
+	}
+}
+
+func lookupCommand(args []string) (command, []string, bool) {
+	if len(args) == 0 {
+		return command{}, nil, false
+	}
+	subs, ok := commands[args[0]]
+	if !ok {
+		return command{}, nil, false
+	}
+	if cmd, ok := subs[""]; ok {
+		return cmd, args[1:], true
+	}
+	if len(args) < 2 {
+		return command{}, nil, false
+	}
+	cmd, ok := subs[args[1]]
+	return cmd, args[2:], ok
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: omctl [global flags] <command> [flags] [args]\n\nCommands:\n")
+	for _, name := range []string{"ticket", "pool", "backfill", "fetch", "dryrun"} {
+		for _, sub := range []string{"", "create", "get", "delete", "watch", "tickets", "backfills", "cleanup"} {
+			if cmd, ok := commands[name][sub]; ok {
+				fmt.Fprintf(os.Stderr, "  %s %s\n", joinNonEmpty(name, sub), cmd.usage)
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	flag.PrintDefaults()
+}
+
+func joinNonEmpty(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return a + " " + b
+}
+
+func (c *omctl) frontend() (pb.FrontendServiceClient, error) {
+	return omutils.NewOMFrontendClient(c.frontendAddr, omutils.WithTLS(&c.tls))
+}
+
+func (c *omctl) backend() (pb.BackendServiceClient, error) {
+	return omutils.NewOMBackendClient(c.backendAddr, omutils.WithTLS(&c.tls))
+}
+
+func (c *omctl) query() (pb.QueryServiceClient, error) {
+	return omutils.NewOMQueryClient(c.queryAddr, omutils.WithTLS(&c.tls))
+}
+
+// parseID parses the flags of a command that takes exactly one ID argument.
+func parseID(name string, args []string) (string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s requires exactly one ID", name)
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// printer prints Open Match resources as a table or JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func (p *printer) Tickets(tickets []*pb.Ticket) error {
+	if p.json {
+		return printJSON(p.w, tickets)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tSEARCH FIELDS\tASSIGNMENT")
+	for _, t := range tickets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Id, formatTime(t.CreateTime), formatSearchFields(t.SearchFields), t.GetAssignment().GetConnection())
+	}
+	return tw.Flush()
+}
+
+func (p *printer) Backfills(backfills []*pb.Backfill) error {
+	if p.json {
+		return printJSON(p.w, backfills)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tGENERATION\tOPEN SLOTS\tCREATED\tSEARCH FIELDS")
+	for _, b := range backfills {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", b.Id, b.Generation, formatOpenSlots(b), formatTime(b.CreateTime), formatSearchFields(b.SearchFields))
+	}
+	return tw.Flush()
+}
+
+func (p *printer) Matches(matches []*pb.Match) error {
+	if p.json {
+		return printJSON(p.w, matches)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MATCH ID\tPROFILE\tTICKETS\tBACKFILL\tOPEN SLOTS\tALLOCATE GAMESERVER")
+	for _, m := range matches {
+		var tids []string
+		for _, t := range m.Tickets {
+			tids = append(tids, t.Id)
+		}
+		backfillID, openSlots := "-", "-"
+		if m.Backfill != nil {
+			backfillID = m.Backfill.Id
+			if backfillID == "" {
+				backfillID = "(new)"
+			}
+			openSlots = formatOpenSlots(m.Backfill)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\n", m.MatchId, m.MatchProfile, strings.Join(tids, ","), backfillID, openSlots, m.AllocateGameserver)
+	}
+	return tw.Flush()
+}
+
+func (p *printer) Assignment(ticketID string, as *pb.Assignment) error {
+	if p.json {
+		b, err := protojson.Marshal(as)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(p.w).Encode(struct {
+			TicketID   string          `json:"ticketId"`
+			Assignment json.RawMessage `json:"assignment"`
+		}{TicketID: ticketID, Assignment: b})
+	}
+	_, err := fmt.Fprintf(p.w, "ticket %s assigned to %s\n", ticketID, as.GetConnection())
+	return err
+}
+
+func (p *printer) Deleted(kind, id string) error {
+	if p.json {
+		return json.NewEncoder(p.w).Encode(map[string]string{"deleted": kind, "id": id})
+	}
+	_, err := fmt.Fprintf(p.w, "%s %s deleted\n", kind, id)
+	return err
+}
+
+func (p *printer) Cleanup(pool string, tickets, backfills int) error {
+	if p.json {
+		return json.NewEncoder(p.w).Encode(map[string]any{"pool": pool, "deletedTickets": tickets, "deletedBackfills": backfills})
+	}
+	_, err := fmt.Fprintf(p.w, "pool %s cleaned up (tickets: %d, backfills: %d)\n", pool, tickets, backfills)
+	return err
+}
+
+// printJSON prints messages as a JSON array.
+func printJSON[M proto.Message](w io.Writer, msgs []M) error {
+	raws := []json.RawMessage{}
+	for _, m := range msgs {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		raws = append(raws, b)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(raws)
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
+
+func formatOpenSlots(b *pb.Backfill) string {
+	openSlots, err := omutils.GetOpenSlots(b)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", openSlots)
+}
+
+func formatSearchFields(sf *pb.SearchFields) string {
+	var fields []string
+	for k, v := range sf.GetStringArgs() {
+		fields = append(fields, fmt.Sprintf("%s=%s", k, v))
+	}
+	for k, v := range sf.GetDoubleArgs() {
+		fields = append(fields, fmt.Sprintf("%s=%g", k, v))
+	}
+	sort.Strings(fields)
+	for _, tag := range sf.GetTags() {
+		fields = append(fields, "#"+tag)
+	}
+	if len(fields) == 0 {
+		return "-"
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/matchfunction"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func queryTickets(ctx context.Context, c *omctl, args []string) error {
+	pool, err := parsePool("pool tickets", args)
+	if err != nil {
+		return err
+	}
+	qs, err := c.query()
+	if err != nil {
+		return err
+	}
+	tickets, err := matchfunction.QueryPool(ctx, qs, pool)
+	if err != nil {
+		return fmt.Errorf("failed to query tickets: %w", err)
+	}
+	return c.printer.Tickets(tickets)
+}
+
+func queryBackfills(ctx context.Context, c *omctl, args []string) error {
+	pool, err := parsePool("pool backfills", args)
+	if err != nil {
+		return err
+	}
+	qs, err := c.query()
+	if err != nil {
+		return err
+	}
+	backfills, err := matchfunction.QueryBackfillPool(ctx, qs, pool)
+	if err != nil {
+		return fmt.Errorf("failed to query backfills: %w", err)
+	}
+	return c.printer.Backfills(backfills)
+}
+
+func parsePool(name string, args []string) (*pb.Pool, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var pf poolFlags
+	pf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return pf.pool()
+}
+
+func cleanupPool(ctx context.Context, c *omctl, args []string) error {
+	pool, err := parsePool("pool cleanup", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	qs, err := c.query()
+	if err != nil {
+		return err
+	}
+	tickets, backfills, err := omutils.CleanupPool(ctx, fe, qs, pool)
+	if err != nil {
+		return err
+	}
+	return c.printer.Cleanup(pool.Name, tickets, backfills)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func createTicket(ctx context.Context, c *omctl, args []string) error {
+	fs := flag.NewFlagSet("ticket create", flag.ContinueOnError)
+	var sf searchFieldsFlags
+	sf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	searchFields, err := sf.searchFields()
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	ticket, err := fe.CreateTicket(ctx, &pb.CreateTicketRequest{Ticket: &pb.Ticket{SearchFields: searchFields}})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket: %w", err)
+	}
+	return c.printer.Tickets([]*pb.Ticket{ticket})
+}
+
+func getTicket(ctx context.Context, c *omctl, args []string) error {
+	id, err := parseID("ticket get", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	ticket, err := fe.GetTicket(ctx, &pb.GetTicketRequest{TicketId: id})
+	if err != nil {
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return c.printer.Tickets([]*pb.Ticket{ticket})
+}
+
+func deleteTicket(ctx context.Context, c *omctl, args []string) error {
+	id, err := parseID("ticket delete", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	if _, err := fe.DeleteTicket(ctx, &pb.DeleteTicketRequest{TicketId: id}); err != nil {
+		return fmt.Errorf("failed to delete ticket: %w", err)
+	}
+	return c.printer.Deleted("ticket", id)
+}
+
+// watchAssignments prints each assignment update of the ticket until interrupted.
+func watchAssignments(ctx context.Context, c *omctl, args []string) error {
+	id, err := parseID("ticket watch", args)
+	if err != nil {
+		return err
+	}
+	fe, err := c.frontend()
+	if err != nil {
+		return err
+	}
+	var printErr error
+	err = omutils.WatchAssignments(ctx, fe, id, func(as *pb.Assignment) bool {
+		printErr = c.printer.Assignment(id, as)
+		return printErr == nil
+	})
+	if printErr != nil {
+		return printErr
+	}
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
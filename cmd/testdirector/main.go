@@ -2,16 +2,24 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/leader"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"github.com/castaneai/openmatch-local-dev/omutils/record"
 	"open-match.dev/open-match/pkg/pb"
 )
 
+var logger = logging.Component("director")
+
 var matchProfile = &pb.MatchProfile{
 	Name: "test-profile",
 	Pools: []*pb.Pool{
@@ -20,16 +28,118 @@ var matchProfile = &pb.MatchProfile{
 }
 
 func main() {
+	var recordFile, statusAddr, leaderElect, leaderLock string
+	var reapInterval, reapGracePeriod, reapAckDeadline time.Duration
+	flag.StringVar(&recordFile, "record", "", "A path to record proposals and assignments (JSON Lines)")
+	flag.StringVar(&statusAddr, "status-addr", ":8080", "An address to serve the status of the profiles at /status (empty to disable)")
+	flag.StringVar(&leaderElect, "leader-elect", "", "Elect a leader among the replicas with a lock: 'lease' (Kubernetes Lease) or 'file' (empty to disable)")
+	flag.StringVar(&leaderLock, "leader-lock", "testdirector", "The name of the Lease, or the path of the lock file")
+	flag.DurationVar(&reapInterval, "reap-interval", 0, "An interval to delete orphaned backfills in the pools of the profile (0 to disable)")
+	flag.DurationVar(&reapGracePeriod, "reap-grace-period", 10*time.Second, "How long a backfill without game server is kept after it is created")
+	flag.DurationVar(&reapAckDeadline, "reap-ack-deadline", 5*time.Second, "How long a backfill of a game server can be left unacknowledged before it is reported")
+	flag.Parse()
+	logging.SetupFromEnv()
+
 	backendAddr := "open-match-backend.open-match.svc.cluster.local.:50505"
+	frontendAddr := "open-match-frontend.open-match.svc.cluster.local.:50504"
+	queryAddr := "open-match-query.open-match.svc.cluster.local.:50503"
 	matchFunction := "matchfunction-simple1vs1"
-	log.Printf("start testdirector (backend: %s, profile: %s, matchFunction: %s)", backendAddr, matchProfile.Name, matchFunction)
-	d, err := omutils.NewTestDirector(backendAddr, matchProfile, matchFunction)
+	logger.Info("start testdirector", "backend", backendAddr, logging.Profile(matchProfile.Name), "matchfunction", matchFunction)
+	var recorder *record.Recorder
+	if recordFile != "" {
+		r, err := record.NewRecorder(recordFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to start recording", "error", err)
+		}
+		defer r.Close()
+		recorder = r
+	}
+	tlsOption := omutils.WithTLS(omutils.TLSConfigFromEnv())
+	d, err := omutils.NewTestDirector(backendAddr, matchProfile, matchFunction,
+		omutils.WithClientOptions(tlsOption),
+		omutils.WithObservers(recorder.ObserveMatch))
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal(logger, "failed to create director", "error", err)
+	}
+	var reaper *omutils.BackfillReaper
+	if reapInterval > 0 {
+		reaper, err = newBackfillReaper(frontendAddr, queryAddr, tlsOption, &omutils.BackfillReaperConfig{
+			Pools:       matchProfile.Pools,
+			GracePeriod: reapGracePeriod,
+			AckDeadline: reapAckDeadline,
+		})
+		if err != nil {
+			logging.Fatal(logger, "failed to create backfill reaper", "error", err)
+		}
+	}
+	if statusAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/status", omutils.DirectorStatusHandler(d))
+		go func() {
+			if err := http.ListenAndServe(statusAddr, mux); err != nil {
+				logger.Error("failed to serve status", "error", err)
+			}
+		}()
 	}
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
-	if err := d.Run(ctx, 1*time.Second); err != nil {
-		log.Fatal(err)
+	run := func(ctx context.Context) {
+		var wg sync.WaitGroup
+		if reaper != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := reaper.Run(ctx, reapInterval); err != nil {
+					logger.Error("failed to run backfill reaper", "error", err)
+				}
+			}()
+		}
+		if err := d.Run(ctx, 1*time.Second); err != nil {
+			logger.Error("failed to run director", "error", err)
+		}
+		wg.Wait()
+	}
+	if leaderElect == "" {
+		run(ctx)
+		return
+	}
+	lock, err := newLeaderLock(leaderElect, leaderLock)
+	if err != nil {
+		logging.Fatal(logger, "failed to create leader lock", "error", err)
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		logging.Fatal(logger, "failed to get hostname", "error", err)
+	}
+	// Only the leader fetches matches and reaps backfills, so that the replicas don't fetch the same profile twice.
+	if err := leader.Run(ctx, lock, &leader.Config{Identity: identity}, run); err != nil {
+		logging.Fatal(logger, "failed to run leader election", "error", err)
+	}
+}
+
+// newBackfillReaper returns a reaper of the backfills in the pools of the test director.
+// Its dummy game servers own no backfills, so every backfill in the pools is deleted after the grace period,
+// including those of other game servers; the pools without filters are rejected.
+func newBackfillReaper(frontendAddr, queryAddr string, tlsOption omutils.ClientOption, config *omutils.BackfillReaperConfig) (*omutils.BackfillReaper, error) {
+	frontend, err := omutils.NewOMFrontendClient(frontendAddr, tlsOption)
+	if err != nil {
+		return nil, err
+	}
+	query, err := omutils.NewOMQueryClient(queryAddr, tlsOption)
+	if err != nil {
+		return nil, err
+	}
+	noGameServers := omutils.BackfillOwnerFunc(func() map[string]time.Time { return nil })
+	return omutils.NewBackfillReaper(frontend, query, noGameServers, config)
+}
+
+func newLeaderLock(kind, name string) (leader.Lock, error) {
+	switch kind {
+	case "lease":
+		return leader.NewInClusterLease(name)
+	case "file":
+		return leader.NewFileLock(name), nil
+	default:
+		return nil, fmt.Errorf("unknown leader lock: %s", kind)
 	}
 }
@@ -1,9 +1,9 @@
 module github.com/castaneai/openmatch-local-dev
 
-go 1.19
+go 1.21
 
 require (
-	github.com/castaneai/omtools v0.0.0-20230419091957-dada78a3fda1
+	github.com/bojand/hri v1.1.0
 	github.com/davecgh/go-spew v1.1.1
 	github.com/google/uuid v1.3.0
 	github.com/stretchr/testify v1.8.1
@@ -14,7 +14,6 @@ require (
 )
 
 require (
-	github.com/bojand/hri v1.1.0 // indirect
 	github.com/go-chi/chi/v5 v5.0.7 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.15.2 // indirect
@@ -1,86 +1,73 @@
 package main
 
 import (
+	"context"
 	"testing"
 
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mftest"
+	"github.com/castaneai/openmatch-local-dev/omutils"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"open-match.dev/open-match/pkg/pb"
 )
 
-func TestMakeMatches(t *testing.T) {
-	pool := &pb.Pool{
-		Name: "test-pool",
-	}
-	profile := &pb.MatchProfile{
-		Name:  "test-profile",
-		Pools: []*pb.Pool{pool},
-	}
+var testProfile = &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{{Name: "test-pool"}}}
 
-	t.Run("remaining tickets will make match with backfill", func(t *testing.T) {
-		poolTickets := map[string][]*pb.Ticket{
-			pool.Name: {
-				&pb.Ticket{Id: "ticket-1"},
-				&pb.Ticket{Id: "ticket-2"},
-			},
-		}
-		poolBackfills := map[string][]*pb.Backfill{}
-		matches, err := makeMatches(profile, poolTickets, poolBackfills)
-		assert.NoError(t, err)
-		assert.Len(t, matches, 1)
-		assert.Len(t, matches[0].Tickets, len(poolTickets[pool.Name]))
-		assert.NotNil(t, matches[0].Backfill)
-		assert.True(t, matches[0].AllocateGameserver)
-	})
+func newHarness(t *testing.T, fixture *mftest.Fixture) *mftest.Harness {
+	h := mftest.New(t, fixture)
+	h.Serve(&matchFunctionService{qsc: h.QueryClient()})
+	return h
+}
 
-	t.Run("fulfilled tickets will make full-match without backfill", func(t *testing.T) {
-		poolTickets := map[string][]*pb.Ticket{
-			pool.Name: {
-				&pb.Ticket{Id: "ticket-1"},
-				&pb.Ticket{Id: "ticket-2"},
-				&pb.Ticket{Id: "ticket-3"},
-			},
-		}
-		poolBackfills := map[string][]*pb.Backfill{}
-		matches, err := makeMatches(profile, poolTickets, poolBackfills)
-		assert.NoError(t, err)
-		assert.Len(t, matches, 1)
-		assert.Len(t, matches[0].Tickets, len(poolTickets[pool.Name]))
-		assert.Nil(t, matches[0].Backfill)
-		assert.True(t, matches[0].AllocateGameserver)
+// TestScenarios pins the proposals of the scenarios in matchfunction/testdata/scenarios.
+// Run `make update-golden` to regenerate the golden files.
+func TestScenarios(t *testing.T) {
+	mftest.RunScenarios(t, "../testdata/scenarios", "testdata", func(qsc pb.QueryServiceClient) pb.MatchFunctionServer {
+		return &matchFunctionService{qsc: qsc}
+	}, omutils.PlayersPerMatch)
+}
+
+func TestRun(t *testing.T) {
+	h := newHarness(t, &mftest.Fixture{
+		PoolTickets: map[string][]*pb.Ticket{
+			"test-pool": mftest.Tickets("t1", "t2", "t3", "t4", "t5"),
+		},
+		PoolBackfills: map[string][]*pb.Backfill{
+			"test-pool": {mftest.Backfill(t, "b1", 1)},
+		},
 	})
+	matches := h.MustRun(testProfile)
+	mftest.AssertMatchedTickets(t, matches, "t1", "t2", "t3", "t4", "t5")
+	h.AssertValidProposals(testProfile, matches, omutils.PlayersPerMatch)
 
-	t.Run(" tickets will make match without backfill", func(t *testing.T) {
-		poolTickets := map[string][]*pb.Ticket{
-			pool.Name: {
-				&pb.Ticket{Id: "ticket-1"},
-			},
-		}
-		numTickets := len(poolTickets[pool.Name])
-		poolBackfills := map[string][]*pb.Backfill{}
-		matches, err := makeMatches(profile, poolTickets, poolBackfills)
+	// t1 fills the existing backfill, t2-t4 make a full match, and t5 waits in a new backfill.
+	if assert.Len(t, matches, 3) {
+		assert.Equal(t, "b1", matches[0].Backfill.GetId())
+		assert.False(t, matches[0].AllocateGameserver)
+		assert.Nil(t, matches[1].Backfill)
+		assert.True(t, matches[1].AllocateGameserver)
+		assert.Empty(t, matches[2].Backfill.GetId())
+		openSlots, err := omutils.GetOpenSlots(matches[2].Backfill)
 		assert.NoError(t, err)
-		assert.Len(t, matches, 1)
-		assert.Len(t, matches[0].Tickets, numTickets)
-		assert.Equal(t, "ticket-1", matches[0].Tickets[0].Id)
-		assert.NotNil(t, matches[0].Backfill)
-		assert.True(t, matches[0].AllocateGameserver)
-
-		poolBackfills[pool.Name] = nil
-		poolBackfills[pool.Name] = append(poolBackfills[pool.Name], matches[0].Backfill)
-
-		poolTickets = map[string][]*pb.Ticket{
-			pool.Name: {
-				&pb.Ticket{Id: "ticket-2"},
-			},
-		}
-		numTickets = len(poolTickets[pool.Name])
+		assert.Equal(t, int32(omutils.PlayersPerMatch-1), openSlots)
+	}
+}
 
-		matches, err = makeMatches(profile, poolTickets, poolBackfills)
-		assert.NoError(t, err)
-		assert.Len(t, matches, 1)
-		assert.Len(t, matches[0].Tickets, numTickets)
-		assert.NotNil(t, matches[0].Backfill)
-		assert.False(t, matches[0].AllocateGameserver)
+func TestRunQueryError(t *testing.T) {
+	t.Run("tickets", func(t *testing.T) {
+		h := newHarness(t, nil)
+		h.Query.FailTickets("test-pool", status.Error(codes.Unavailable, "query service is down"))
+		_, err := h.Run(context.Background(), testProfile)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+	t.Run("backfills", func(t *testing.T) {
+		h := newHarness(t, &mftest.Fixture{PoolTickets: map[string][]*pb.Ticket{
+			"test-pool": mftest.Tickets("t1"),
+		}})
+		h.Query.FailBackfills("test-pool", status.Error(codes.Unavailable, "query service is down"))
+		matches, err := h.Run(context.Background(), testProfile)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Empty(t, matches)
 	})
-
 }
@@ -0,0 +1,213 @@
+// Package mftest tests a match function end-to-end without a cluster.
+// It serves the match function over an in-memory connection with a fake QueryService seeded from a Fixture,
+// runs it with a profile and collects the streamed proposals.
+//
+//	h := mftest.New(t, &mftest.Fixture{PoolTickets: map[string][]*pb.Ticket{"pool": mftest.Tickets("t1", "t2")}})
+//	h.Serve(&matchFunctionService{qsc: h.QueryClient()})
+//	matches := h.MustRun(profile)
+package mftest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const bufSize = 1024 * 1024
+
+// Fixture is the tickets and backfills returned by the fake QueryService for each pool name.
+type Fixture struct {
+	PoolTickets   map[string][]*pb.Ticket
+	PoolBackfills map[string][]*pb.Backfill
+}
+
+// Tickets returns tickets with the IDs.
+func Tickets(ids ...string) []*pb.Ticket {
+	var tickets []*pb.Ticket
+	for _, id := range ids {
+		tickets = append(tickets, &pb.Ticket{Id: id})
+	}
+	return tickets
+}
+
+// Backfill returns a backfill with the open slots.
+func Backfill(t testing.TB, id string, openSlots int32) *pb.Backfill {
+	t.Helper()
+	b := &pb.Backfill{Id: id}
+	if err := omutils.SetOpenSlots(b, openSlots); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// FakeQueryService returns the tickets and backfills of the fixture by pool name, ignoring the filters of the pool.
+type FakeQueryService struct {
+	pb.UnimplementedQueryServiceServer
+
+	mu           sync.Mutex
+	fixture      *Fixture
+	ticketErrs   map[string]error
+	backfillErrs map[string]error
+}
+
+// FailTickets makes QueryTickets of the pool fail with err.
+func (s *FakeQueryService) FailTickets(pool string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ticketErrs[pool] = err
+}
+
+// FailBackfills makes QueryBackfills of the pool fail with err.
+func (s *FakeQueryService) FailBackfills(pool string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backfillErrs[pool] = err
+}
+
+func (s *FakeQueryService) QueryTickets(req *pb.QueryTicketsRequest, stream pb.QueryService_QueryTicketsServer) error {
+	s.mu.Lock()
+	tickets, err := s.fixture.PoolTickets[req.Pool.GetName()], s.ticketErrs[req.Pool.GetName()]
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(tickets) == 0 {
+		return nil
+	}
+	return stream.Send(&pb.QueryTicketsResponse{Tickets: tickets})
+}
+
+func (s *FakeQueryService) QueryBackfills(req *pb.QueryBackfillsRequest, stream pb.QueryService_QueryBackfillsServer) error {
+	s.mu.Lock()
+	backfills, err := s.fixture.PoolBackfills[req.Pool.GetName()], s.backfillErrs[req.Pool.GetName()]
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(backfills) == 0 {
+		return nil
+	}
+	return stream.Send(&pb.QueryBackfillsResponse{Backfills: backfills})
+}
+
+type Harness struct {
+	t       testing.TB
+	fixture *Fixture
+	// Query is the fake QueryService to inject errors.
+	Query       *FakeQueryService
+	queryClient pb.QueryServiceClient
+	mfClient    pb.MatchFunctionClient
+}
+
+// New starts the fake QueryService with the fixture. The servers are stopped when the test finishes.
+func New(t testing.TB, fixture *Fixture) *Harness {
+	t.Helper()
+	if fixture == nil {
+		fixture = &Fixture{}
+	}
+	qs := &FakeQueryService{fixture: fixture, ticketErrs: map[string]error{}, backfillErrs: map[string]error{}}
+	cc := serve(t, func(s *grpc.Server) { pb.RegisterQueryServiceServer(s, qs) })
+	return &Harness{
+		t:           t,
+		fixture:     fixture,
+		Query:       qs,
+		queryClient: pb.NewQueryServiceClient(cc),
+	}
+}
+
+// QueryClient returns the client of the fake QueryService to construct the match function.
+func (h *Harness) QueryClient() pb.QueryServiceClient {
+	return h.queryClient
+}
+
+// Serve starts the match function.
+func (h *Harness) Serve(mf pb.MatchFunctionServer) {
+	h.t.Helper()
+	cc := serve(h.t, func(s *grpc.Server) { pb.RegisterMatchFunctionServer(s, mf) })
+	h.mfClient = pb.NewMatchFunctionClient(cc)
+}
+
+// Run runs the match function with the profile and returns the streamed proposals.
+func (h *Harness) Run(ctx context.Context, profile *pb.MatchProfile) ([]*pb.Match, error) {
+	if h.mfClient == nil {
+		return nil, errors.New("match function is not served")
+	}
+	stream, err := h.mfClient.Run(ctx, &pb.RunRequest{Profile: profile})
+	if err != nil {
+		return nil, err
+	}
+	var matches []*pb.Match
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+		matches = append(matches, resp.Proposal)
+	}
+}
+
+// MustRun is Run that fails the test on error.
+func (h *Harness) MustRun(profile *pb.MatchProfile) []*pb.Match {
+	h.t.Helper()
+	matches, err := h.Run(context.Background(), profile)
+	if err != nil {
+		h.t.Fatalf("failed to run match function: %+v", err)
+	}
+	return matches
+}
+
+// AssertValidProposals asserts the proposals pass mmlogic.ValidateProposals against the fixture of the profile's pools.
+func (h *Harness) AssertValidProposals(profile *pb.MatchProfile, matches []*pb.Match, capacity int) bool {
+	h.t.Helper()
+	poolTickets := map[string][]*pb.Ticket{}
+	poolBackfills := map[string][]*pb.Backfill{}
+	for _, pool := range profile.Pools {
+		poolTickets[pool.Name] = h.fixture.PoolTickets[pool.Name]
+		poolBackfills[pool.Name] = h.fixture.PoolBackfills[pool.Name]
+	}
+	return assert.NoError(h.t, mmlogic.ValidateProposals(profile, poolTickets, poolBackfills, matches, capacity))
+}
+
+// AssertMatchedTickets asserts the proposals contain exactly the tickets, in any order.
+func AssertMatchedTickets(t testing.TB, matches []*pb.Match, ticketIDs ...string) bool {
+	t.Helper()
+	var matched []string
+	for _, match := range matches {
+		for _, ticket := range match.Tickets {
+			matched = append(matched, ticket.Id)
+		}
+	}
+	return assert.ElementsMatch(t, ticketIDs, matched)
+}
+
+func serve(t testing.TB, register func(s *grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	register(s)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.Dial("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
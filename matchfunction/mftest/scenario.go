@@ -0,0 +1,212 @@
+package mftest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var update = flag.Bool("update", false, "Update the golden files of the scenario tests")
+
+// Scenario is the input of a match function in JSON:
+//
+//	{
+//	  "profile": "test-profile",
+//	  "pools": [{"name": "asia", "stringEquals": {"region": "asia"}}],
+//	  "tickets": {"asia": [{"id": "t1", "doubleArgs": {"skill": 1500}}]},
+//	  "backfills": {"asia": [{"id": "b1", "openSlots": 1}]}
+//	}
+//
+// The fake QueryService returns the tickets and backfills by pool name, so they don't have to match the filters.
+type Scenario struct {
+	Profile   string                        `json:"profile"`
+	Pools     []ScenarioPool                `json:"pools"`
+	Tickets   map[string][]ScenarioTicket   `json:"tickets,omitempty"`
+	Backfills map[string][]ScenarioBackfill `json:"backfills,omitempty"`
+}
+
+type ScenarioPool struct {
+	Name         string            `json:"name"`
+	Tags         []string          `json:"tags,omitempty"`
+	StringEquals map[string]string `json:"stringEquals,omitempty"`
+}
+
+type ScenarioTicket struct {
+	ID         string             `json:"id"`
+	StringArgs map[string]string  `json:"stringArgs,omitempty"`
+	DoubleArgs map[string]float64 `json:"doubleArgs,omitempty"`
+	Tags       []string           `json:"tags,omitempty"`
+}
+
+type ScenarioBackfill struct {
+	ID        string `json:"id"`
+	OpenSlots int32  `json:"openSlots"`
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	var sc Scenario
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if len(sc.Pools) == 0 {
+		return nil, fmt.Errorf("scenario %s has no pools", path)
+	}
+	return &sc, nil
+}
+
+func (sc *Scenario) MatchProfile() *pb.MatchProfile {
+	profile := &pb.MatchProfile{Name: sc.Profile}
+	for _, p := range sc.Pools {
+		pool := &pb.Pool{Name: p.Name}
+		for _, tag := range p.Tags {
+			pool.TagPresentFilters = append(pool.TagPresentFilters, &pb.TagPresentFilter{Tag: tag})
+		}
+		for _, k := range sortedKeys(p.StringEquals) {
+			pool.StringEqualsFilters = append(pool.StringEqualsFilters, &pb.StringEqualsFilter{StringArg: k, Value: p.StringEquals[k]})
+		}
+		profile.Pools = append(profile.Pools, pool)
+	}
+	return profile
+}
+
+func (sc *Scenario) Fixture(t testing.TB) *Fixture {
+	t.Helper()
+	f := &Fixture{PoolTickets: map[string][]*pb.Ticket{}, PoolBackfills: map[string][]*pb.Backfill{}}
+	for pool, tickets := range sc.Tickets {
+		for _, ticket := range tickets {
+			f.PoolTickets[pool] = append(f.PoolTickets[pool], &pb.Ticket{
+				Id: ticket.ID,
+				SearchFields: &pb.SearchFields{
+					StringArgs: ticket.StringArgs,
+					DoubleArgs: ticket.DoubleArgs,
+					Tags:       ticket.Tags,
+				},
+			})
+		}
+	}
+	for pool, backfills := range sc.Backfills {
+		for _, backfill := range backfills {
+			f.PoolBackfills[pool] = append(f.PoolBackfills[pool], Backfill(t, backfill.ID, backfill.OpenSlots))
+		}
+	}
+	return f
+}
+
+// Golden is the proposals of a scenario without the random parts (e.g. match IDs),
+// sorted so that algorithm changes are reviewable as diffs.
+type Golden struct {
+	Matches []GoldenMatch `json:"matches"`
+}
+
+type GoldenMatch struct {
+	Tickets []string `json:"tickets"`
+	// Backfill is the ID of the existing backfill, "(new)" for a new backfill, or empty without backfill.
+	Backfill           string `json:"backfill,omitempty"`
+	OpenSlots          *int32 `json:"openSlots,omitempty"`
+	AllocateGameserver bool   `json:"allocateGameserver"`
+}
+
+func NewGolden(matches []*pb.Match) (*Golden, error) {
+	g := &Golden{Matches: []GoldenMatch{}}
+	for _, match := range matches {
+		gm := GoldenMatch{AllocateGameserver: match.AllocateGameserver, Tickets: []string{}}
+		for _, ticket := range match.Tickets {
+			gm.Tickets = append(gm.Tickets, ticket.Id)
+		}
+		if match.Backfill != nil {
+			gm.Backfill = match.Backfill.Id
+			if gm.Backfill == "" {
+				gm.Backfill = "(new)"
+			}
+			openSlots, err := omutils.GetOpenSlots(match.Backfill)
+			if err != nil {
+				return nil, err
+			}
+			gm.OpenSlots = &openSlots
+		}
+		g.Matches = append(g.Matches, gm)
+	}
+	sort.Slice(g.Matches, func(i, j int) bool {
+		return strings.Join(g.Matches[i].Tickets, ",") < strings.Join(g.Matches[j].Tickets, ",")
+	})
+	return g, nil
+}
+
+// RunScenarios runs the match function with each scenario (*.json) in scenarioDir,
+// and compares the proposals with the golden file of the same name in goldenDir.
+// The golden files are (re)written with the -update flag.
+func RunScenarios(t *testing.T, scenarioDir, goldenDir string, newMatchFunction func(qsc pb.QueryServiceClient) pb.MatchFunctionServer, capacity int) {
+	paths, err := filepath.Glob(filepath.Join(scenarioDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no scenarios in %s", scenarioDir)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			sc, err := LoadScenario(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := New(t, sc.Fixture(t))
+			h.Serve(newMatchFunction(h.QueryClient()))
+			profile := sc.MatchProfile()
+			matches := h.MustRun(profile)
+			h.AssertValidProposals(profile, matches, capacity)
+
+			golden, err := NewGolden(matches)
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertGolden(t, filepath.Join(goldenDir, name+".golden.json"), golden)
+		})
+	}
+}
+
+// AssertGolden compares v in JSON with the golden file, or writes it with the -update flag.
+func AssertGolden(t testing.TB, path string, v any) bool {
+	t.Helper()
+	actual, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %+v", err)
+	}
+	return assert.Equal(t, string(expected), string(actual), "golden file %s is outdated (run with -update if the change is intended)", path)
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package mmlogic
+
+import (
+	"fmt"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// Backfill3 makes matches of omutils.PlayersPerMatch players.
+// Tickets first fill the open slots of the existing backfills, then make full matches,
+// and the remaining tickets make a match with a new backfill to wait for more players.
+func Backfill3(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error) {
+	var matches []*pb.Match
+	// A ticket or a backfill can be in more than one pool, but it must be in at most one match.
+	matchedTickets := map[string]struct{}{}
+	matchedBackfills := map[string]struct{}{}
+
+	// First, creating matches with the existing backfills.
+	for pool, tickets := range poolTickets {
+		tickets = unmatchedTickets(tickets, matchedTickets)
+		backfills := unmatchedBackfills(poolBackfills[pool], matchedBackfills)
+
+		newMatches, remainingTickets, err := handleBackfills(profile, tickets, backfills)
+		if err != nil {
+			return nil, err
+		}
+
+		// Second, creating full-matches with tickets
+		fullMatches, remainingTickets := makeFullMatches(profile, remainingTickets)
+		newMatches = append(newMatches, fullMatches...)
+
+		if len(remainingTickets) > 0 {
+			// Third, the remaining tickets will make matches with backfill
+			remainingMatch, err := makeMatchWithBackfill(profile, findPool(profile, pool), remainingTickets)
+			if err != nil {
+				return nil, err
+			}
+			newMatches = append(newMatches, remainingMatch)
+		}
+
+		for _, match := range newMatches {
+			for _, ticket := range match.Tickets {
+				matchedTickets[ticket.Id] = struct{}{}
+			}
+			if match.Backfill.GetId() != "" {
+				matchedBackfills[match.Backfill.Id] = struct{}{}
+			}
+		}
+		matches = append(matches, newMatches...)
+	}
+
+	return matches, nil
+}
+
+func unmatchedTickets(tickets []*pb.Ticket, matched map[string]struct{}) []*pb.Ticket {
+	var unmatched []*pb.Ticket
+	for _, ticket := range tickets {
+		if _, ok := matched[ticket.Id]; !ok {
+			unmatched = append(unmatched, ticket)
+		}
+	}
+	return unmatched
+}
+
+func unmatchedBackfills(backfills []*pb.Backfill, matched map[string]struct{}) []*pb.Backfill {
+	var unmatched []*pb.Backfill
+	for _, backfill := range backfills {
+		if _, ok := matched[backfill.Id]; !ok {
+			unmatched = append(unmatched, backfill)
+		}
+	}
+	return unmatched
+}
+
+func makeFullMatches(profile *pb.MatchProfile, tickets []*pb.Ticket) ([]*pb.Match, []*pb.Ticket) {
+	var matches []*pb.Match
+	for len(tickets) >= omutils.PlayersPerMatch {
+		match := newMatch(profile, tickets[:omutils.PlayersPerMatch], nil)
+		match.AllocateGameserver = true
+		tickets = tickets[omutils.PlayersPerMatch:]
+		matches = append(matches, match)
+	}
+	return matches, tickets
+}
+
+func handleBackfills(profile *pb.MatchProfile, tickets []*pb.Ticket, backfills []*pb.Backfill) ([]*pb.Match, []*pb.Ticket, error) {
+	var matches []*pb.Match
+
+	for _, backfill := range backfills {
+		openSlots, err := omutils.GetOpenSlots(backfill)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var matchTickets []*pb.Ticket
+		for openSlots > 0 && len(tickets) > 0 {
+			matchTickets = append(matchTickets, tickets[0])
+			tickets = tickets[1:]
+			openSlots--
+		}
+
+		if len(matchTickets) > 0 {
+			if err := omutils.SetOpenSlots(backfill, openSlots); err != nil {
+				return nil, nil, err
+			}
+			matches = append(matches, newMatch(profile, matchTickets, backfill))
+		}
+	}
+	return matches, tickets, nil
+}
+
+func makeMatchWithBackfill(profile *pb.MatchProfile, pool *pb.Pool, tickets []*pb.Ticket) (*pb.Match, error) {
+	if len(tickets) == 0 {
+		return nil, fmt.Errorf("tickets are required")
+	}
+	if len(tickets) > omutils.PlayersPerMatch {
+		return nil, fmt.Errorf("too many tickets")
+	}
+	// The backfill has the SearchFields of the pool so that it is found by querying the same pool.
+	backfill, err := newBackfill(omutils.SearchFieldsForPool(pool), omutils.PlayersPerMatch-len(tickets))
+	if err != nil {
+		return nil, err
+	}
+	match := newMatch(profile, tickets, backfill)
+	match.AllocateGameserver = true
+	return match, nil
+}
+
+func findPool(profile *pb.MatchProfile, name string) *pb.Pool {
+	for _, pool := range profile.Pools {
+		if pool.Name == name {
+			return pool
+		}
+	}
+	return nil
+}
+
+func newBackfill(searchFields *pb.SearchFields, openSlots int) (*pb.Backfill, error) {
+	b := &pb.Backfill{
+		SearchFields: searchFields,
+		CreateTime:   timestamppb.Now(),
+		Generation:   0,
+	}
+	if err := omutils.SetOpenSlots(b, int32(openSlots)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,194 @@
+package mmlogic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestBackfill3(t *testing.T) {
+	pool := &pb.Pool{
+		Name: "test-pool",
+	}
+	profile := &pb.MatchProfile{
+		Name:  "test-profile",
+		Pools: []*pb.Pool{pool},
+	}
+
+	t.Run("remaining tickets will make match with backfill", func(t *testing.T) {
+		poolTickets := map[string][]*pb.Ticket{
+			pool.Name: {
+				&pb.Ticket{Id: "ticket-1"},
+				&pb.Ticket{Id: "ticket-2"},
+			},
+		}
+		poolBackfills := map[string][]*pb.Backfill{}
+		matches, err := Backfill3(profile, poolTickets, poolBackfills)
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Len(t, matches[0].Tickets, len(poolTickets[pool.Name]))
+		assert.NotNil(t, matches[0].Backfill)
+		assert.True(t, matches[0].AllocateGameserver)
+		assert.NoError(t, ValidateProposals(profile, poolTickets, poolBackfills, matches, 3))
+	})
+
+	t.Run("fulfilled tickets will make full-match without backfill", func(t *testing.T) {
+		poolTickets := map[string][]*pb.Ticket{
+			pool.Name: {
+				&pb.Ticket{Id: "ticket-1"},
+				&pb.Ticket{Id: "ticket-2"},
+				&pb.Ticket{Id: "ticket-3"},
+			},
+		}
+		poolBackfills := map[string][]*pb.Backfill{}
+		matches, err := Backfill3(profile, poolTickets, poolBackfills)
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Len(t, matches[0].Tickets, len(poolTickets[pool.Name]))
+		assert.Nil(t, matches[0].Backfill)
+		assert.True(t, matches[0].AllocateGameserver)
+		assert.NoError(t, ValidateProposals(profile, poolTickets, poolBackfills, matches, 3))
+	})
+
+	t.Run(" tickets will make match without backfill", func(t *testing.T) {
+		poolTickets := map[string][]*pb.Ticket{
+			pool.Name: {
+				&pb.Ticket{Id: "ticket-1"},
+			},
+		}
+		numTickets := len(poolTickets[pool.Name])
+		poolBackfills := map[string][]*pb.Backfill{}
+		matches, err := Backfill3(profile, poolTickets, poolBackfills)
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Len(t, matches[0].Tickets, numTickets)
+		assert.Equal(t, "ticket-1", matches[0].Tickets[0].Id)
+		assert.NotNil(t, matches[0].Backfill)
+		assert.True(t, matches[0].AllocateGameserver)
+		assert.NoError(t, ValidateProposals(profile, poolTickets, poolBackfills, matches, 3))
+
+		// Open Match assigns the ID when the director creates the backfill.
+		matches[0].Backfill.Id = "backfill-1"
+		poolBackfills[pool.Name] = nil
+		poolBackfills[pool.Name] = append(poolBackfills[pool.Name], matches[0].Backfill)
+
+		poolTickets = map[string][]*pb.Ticket{
+			pool.Name: {
+				&pb.Ticket{Id: "ticket-2"},
+			},
+		}
+		numTickets = len(poolTickets[pool.Name])
+
+		matches, err = Backfill3(profile, poolTickets, poolBackfills)
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Len(t, matches[0].Tickets, numTickets)
+		assert.NotNil(t, matches[0].Backfill)
+		assert.False(t, matches[0].AllocateGameserver)
+		assert.NoError(t, ValidateProposals(profile, poolTickets, poolBackfills, matches, 3))
+	})
+
+}
+
+// FuzzBackfill3 checks the invariants of Backfill3 with arbitrary tickets and backfills in two possibly overlapping pools.
+func FuzzBackfill3(f *testing.F) {
+	f.Add(uint8(2), []byte{}, uint8(0))
+	f.Add(uint8(7), []byte{1, 2, 0}, uint8(0))
+	f.Add(uint8(10), []byte{2, 2, 1, 0}, uint8(3))
+	f.Add(uint8(1), []byte{0, 0}, uint8(1))
+	f.Fuzz(func(t *testing.T, numTickets uint8, backfillSlots []byte, overlap uint8) {
+		profile := &pb.MatchProfile{
+			Name:  "test-profile",
+			Pools: []*pb.Pool{{Name: "pool-a"}, {Name: "pool-b"}},
+		}
+		// pool-a has the first half of the tickets and pool-b has the rest;
+		// overlap moves the boundary of pool-a into pool-b, as a ticket can match the filters of both pools.
+		n := int(numTickets) % 64
+		var tickets []*pb.Ticket
+		for i := 0; i < n; i++ {
+			tickets = append(tickets, &pb.Ticket{Id: fmt.Sprintf("ticket-%d", i)})
+		}
+		half := n / 2
+		poolTickets := map[string][]*pb.Ticket{
+			"pool-a": tickets[:min(half+int(overlap), n)],
+			"pool-b": tickets[half:],
+		}
+		poolBackfills := map[string][]*pb.Backfill{}
+		openSlotsBefore := map[string]int32{}
+		for i, b := range backfillSlots {
+			if i >= 16 {
+				break
+			}
+			// Existing backfills are made by Backfill3, so they have less than PlayersPerMatch open slots.
+			openSlots := int32(b) % omutils.PlayersPerMatch
+			backfill := &pb.Backfill{Id: fmt.Sprintf("backfill-%d", i)}
+			if err := omutils.SetOpenSlots(backfill, openSlots); err != nil {
+				t.Fatal(err)
+			}
+			openSlotsBefore[backfill.Id] = openSlots
+			poolBackfills["pool-a"] = append(poolBackfills["pool-a"], backfill)
+			if i%2 == 1 || overlap > 0 {
+				poolBackfills["pool-b"] = append(poolBackfills["pool-b"], backfill)
+			}
+		}
+
+		matches, err := Backfill3(profile, poolTickets, poolBackfills)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every ticket is used at most once, no match exceeds PlayersPerMatch,
+		// openSlots are never negative, and AllocateGameserver is true exactly for new game servers.
+		if err := ValidateProposals(profile, poolTickets, poolBackfills, matches, omutils.PlayersPerMatch); err != nil {
+			t.Fatal(err)
+		}
+
+		matchedTickets := 0
+		newBackfillMatches := 0
+		for _, match := range matches {
+			matchedTickets += len(match.Tickets)
+			switch {
+			case match.Backfill == nil:
+				assert.Len(t, match.Tickets, omutils.PlayersPerMatch, "a full match has PlayersPerMatch tickets")
+			case match.Backfill.Id == "":
+				newBackfillMatches++
+				openSlots, err := omutils.GetOpenSlots(match.Backfill)
+				assert.NoError(t, err)
+				assert.Equal(t, int32(omutils.PlayersPerMatch-len(match.Tickets)), openSlots, "a new backfill has the slots left by the tickets")
+			default:
+				openSlots, err := omutils.GetOpenSlots(match.Backfill)
+				assert.NoError(t, err)
+				assert.Equal(t, openSlotsBefore[match.Backfill.Id]-int32(len(match.Tickets)), openSlots, "the tickets fill the open slots of the backfill")
+			}
+		}
+		// Every ticket is matched; the leftovers of full matches land in a match with a new backfill.
+		assert.Equal(t, n, matchedTickets)
+		assert.LessOrEqual(t, newBackfillMatches, len(poolTickets))
+		// Existing backfills are filled before new game servers are allocated.
+		// A backfill in only some of the pools may be left open while another pool allocates a new game server,
+		// so the backfills in all pools are checked.
+		usedBackfills := map[string]struct{}{}
+		for _, match := range matches {
+			usedBackfills[match.Backfill.GetId()] = struct{}{}
+		}
+		for _, backfill := range poolBackfills["pool-a"] {
+			if _, ok := usedBackfills[backfill.Id]; ok || !containsBackfill(poolBackfills["pool-b"], backfill) {
+				continue
+			}
+			if openSlotsBefore[backfill.Id] > 0 {
+				assert.Zero(t, newBackfillMatches, "backfill %s is left open while a new backfill is made", backfill.Id)
+			}
+		}
+	})
+}
+
+func containsBackfill(backfills []*pb.Backfill, b *pb.Backfill) bool {
+	for _, backfill := range backfills {
+		if backfill.Id == b.Id {
+			return true
+		}
+	}
+	return false
+}
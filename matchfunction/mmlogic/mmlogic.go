@@ -0,0 +1,23 @@
+// Package mmlogic is the matchmaking logic of the match functions,
+// separated from the gRPC servers so that it can be run offline (e.g. by cmd/mmsim).
+package mmlogic
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// MakeMatchesFunc makes match proposals from the tickets and backfills of each pool.
+type MakeMatchesFunc func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error)
+
+func newMatch(profile *pb.MatchProfile, tickets []*pb.Ticket, backfill *pb.Backfill) *pb.Match {
+	return &pb.Match{
+		MatchId:       fmt.Sprintf("%s-%s", profile.Name, uuid.Must(uuid.NewRandom())),
+		MatchProfile:  profile.Name,
+		MatchFunction: "test",
+		Tickets:       tickets,
+		Backfill:      backfill,
+	}
+}
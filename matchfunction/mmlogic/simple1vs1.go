@@ -0,0 +1,23 @@
+package mmlogic
+
+import (
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	Simple1vs1PlayersPerMatch = 2
+)
+
+// Simple1vs1 makes matches of two tickets in each pool. Backfills are not used.
+func Simple1vs1(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error) {
+	var matches []*pb.Match
+	for _, tickets := range poolTickets {
+		for len(tickets) >= Simple1vs1PlayersPerMatch {
+			match := newMatch(profile, tickets[:Simple1vs1PlayersPerMatch], nil)
+			match.AllocateGameserver = true
+			tickets = tickets[Simple1vs1PlayersPerMatch:]
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,112 @@
+package mmlogic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// ProposalError is an invalid match proposal.
+type ProposalError struct {
+	MatchID string
+	Reason  string
+}
+
+func (e *ProposalError) Error() string {
+	return fmt.Sprintf("invalid proposal '%s': %s", e.MatchID, e.Reason)
+}
+
+// ValidateProposals checks the proposals made from the tickets and backfills of the pools,
+// so that bugs in the matchmaking logic are caught before Open Match rejects them at runtime.
+// capacity is the number of players in a game server (0 skips the capacity checks).
+// It returns all problems joined as *ProposalError.
+func ValidateProposals(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill, matches []*pb.Match, capacity int) error {
+	inputTickets := map[string]struct{}{}
+	for _, tickets := range poolTickets {
+		for _, ticket := range tickets {
+			inputTickets[ticket.Id] = struct{}{}
+		}
+	}
+	inputBackfills := map[string]struct{}{}
+	for _, backfills := range poolBackfills {
+		for _, backfill := range backfills {
+			inputBackfills[backfill.Id] = struct{}{}
+		}
+	}
+
+	var errs []error
+	invalid := func(match *pb.Match, format string, args ...any) {
+		errs = append(errs, &ProposalError{MatchID: match.MatchId, Reason: fmt.Sprintf(format, args...)})
+	}
+	matchIDs := map[string]struct{}{}
+	usedTickets := map[string]string{}
+	usedBackfills := map[string]string{}
+	for _, match := range matches {
+		if match.MatchId == "" {
+			invalid(match, "empty match ID")
+		} else if _, ok := matchIDs[match.MatchId]; ok {
+			invalid(match, "duplicate match ID")
+		}
+		matchIDs[match.MatchId] = struct{}{}
+		if match.MatchProfile != profile.Name {
+			invalid(match, "match profile '%s' differs from '%s'", match.MatchProfile, profile.Name)
+		}
+
+		if len(match.Tickets) == 0 {
+			invalid(match, "no tickets")
+		}
+		for _, ticket := range match.Tickets {
+			if _, ok := inputTickets[ticket.Id]; !ok {
+				invalid(match, "ticket '%s' is not in the pools", ticket.Id)
+			}
+			if other, ok := usedTickets[ticket.Id]; ok {
+				invalid(match, "ticket '%s' is already in match '%s'", ticket.Id, other)
+				continue
+			}
+			usedTickets[ticket.Id] = match.MatchId
+		}
+
+		if match.Backfill == nil {
+			// A match without backfill needs a new game server for its players.
+			if !match.AllocateGameserver {
+				invalid(match, "match without backfill must allocate a game server")
+			}
+			if capacity > 0 && len(match.Tickets) > capacity {
+				invalid(match, "%d tickets exceed the capacity %d", len(match.Tickets), capacity)
+			}
+			continue
+		}
+		openSlots, err := omutils.GetOpenSlots(match.Backfill)
+		if err != nil {
+			invalid(match, "failed to get open slots of the backfill: %+v", err)
+			continue
+		}
+		if openSlots < 0 {
+			invalid(match, "negative open slots %d", openSlots)
+		}
+		if match.Backfill.Id == "" {
+			// A new backfill is created by the director for the new game server.
+			if !match.AllocateGameserver {
+				invalid(match, "match with a new backfill must allocate a game server")
+			}
+			if capacity > 0 && len(match.Tickets)+int(openSlots) > capacity {
+				invalid(match, "%d tickets and %d open slots exceed the capacity %d", len(match.Tickets), openSlots, capacity)
+			}
+			continue
+		}
+		// The players join the game server that already owns the backfill.
+		if match.AllocateGameserver {
+			invalid(match, "match with an existing backfill '%s' must not allocate a game server", match.Backfill.Id)
+		}
+		if _, ok := inputBackfills[match.Backfill.Id]; !ok {
+			invalid(match, "backfill '%s' is not in the pools", match.Backfill.Id)
+		}
+		if other, ok := usedBackfills[match.Backfill.Id]; ok {
+			invalid(match, "backfill '%s' is already in match '%s'", match.Backfill.Id, other)
+		}
+		usedBackfills[match.Backfill.Id] = match.MatchId
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package mmlogic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestValidateProposals(t *testing.T) {
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{{Name: "test-pool"}}}
+	poolTickets := map[string][]*pb.Ticket{
+		"test-pool": {{Id: "t1"}, {Id: "t2"}, {Id: "t3"}, {Id: "t4"}},
+	}
+	backfill := func(id string, openSlots int32) *pb.Backfill {
+		b := &pb.Backfill{Id: id}
+		if err := omutils.SetOpenSlots(b, openSlots); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	poolBackfills := map[string][]*pb.Backfill{
+		"test-pool": {backfill("b1", 2)},
+	}
+	match := func(id string, backfill *pb.Backfill, allocate bool, ticketIDs ...string) *pb.Match {
+		m := &pb.Match{MatchId: id, MatchProfile: profile.Name, Backfill: backfill, AllocateGameserver: allocate}
+		for _, tid := range ticketIDs {
+			m.Tickets = append(m.Tickets, &pb.Ticket{Id: tid})
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name    string
+		matches []*pb.Match
+		reasons []string
+	}{
+		{
+			name: "valid",
+			matches: []*pb.Match{
+				match("m1", nil, true, "t1", "t2", "t3"),
+				match("m2", backfill("b1", 1), false, "t4"),
+			},
+		},
+		{
+			name: "ticket reused in two matches",
+			matches: []*pb.Match{
+				match("m1", backfill("", 1), true, "t1", "t2"),
+				match("m2", backfill("", 1), true, "t2", "t3"),
+			},
+			reasons: []string{"ticket 't2' is already in match 'm1'"},
+		},
+		{
+			name: "unknown ticket and backfill",
+			matches: []*pb.Match{
+				match("m1", backfill("b2", 0), false, "t5"),
+			},
+			reasons: []string{"ticket 't5' is not in the pools", "backfill 'b2' is not in the pools"},
+		},
+		{
+			name: "negative open slots",
+			matches: []*pb.Match{
+				match("m1", backfill("b1", -1), false, "t1", "t2", "t3"),
+			},
+			reasons: []string{"negative open slots -1"},
+		},
+		{
+			name: "allocate game server for an existing backfill",
+			matches: []*pb.Match{
+				match("m1", backfill("b1", 1), true, "t1"),
+			},
+			reasons: []string{"match with an existing backfill 'b1' must not allocate a game server"},
+		},
+		{
+			name: "no game server for a new backfill",
+			matches: []*pb.Match{
+				match("m1", backfill("", 1), false, "t1", "t2"),
+			},
+			reasons: []string{"match with a new backfill must allocate a game server"},
+		},
+		{
+			name: "duplicate match ID",
+			matches: []*pb.Match{
+				match("m1", nil, true, "t1"),
+				match("m1", nil, true, "t2"),
+			},
+			reasons: []string{"duplicate match ID"},
+		},
+		{
+			name: "over capacity",
+			matches: []*pb.Match{
+				match("m1", nil, true, "t1", "t2", "t3", "t4"),
+			},
+			reasons: []string{"4 tickets exceed the capacity 3"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateProposals(profile, poolTickets, poolBackfills, tc.matches, 3)
+			if len(tc.reasons) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var reasons []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var pe *ProposalError
+				if assert.True(t, errors.As(e, &pe)) {
+					reasons = append(reasons, pe.Reason)
+				}
+			}
+			assert.Equal(t, tc.reasons, reasons)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mftest"
+	"github.com/castaneai/openmatch-local-dev/matchfunction/mmlogic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var testProfile = &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{{Name: "test-pool"}}}
+
+func newHarness(t *testing.T, fixture *mftest.Fixture) *mftest.Harness {
+	h := mftest.New(t, fixture)
+	h.Serve(&matchFunctionService{qsc: h.QueryClient()})
+	return h
+}
+
+// TestScenarios pins the proposals of the scenarios in matchfunction/testdata/scenarios.
+// Run `make update-golden` to regenerate the golden files.
+func TestScenarios(t *testing.T) {
+	mftest.RunScenarios(t, "../testdata/scenarios", "testdata", func(qsc pb.QueryServiceClient) pb.MatchFunctionServer {
+		return &matchFunctionService{qsc: qsc}
+	}, mmlogic.Simple1vs1PlayersPerMatch)
+}
+
+func TestRun(t *testing.T) {
+	h := newHarness(t, &mftest.Fixture{PoolTickets: map[string][]*pb.Ticket{
+		"test-pool": mftest.Tickets("t1", "t2", "t3", "t4", "t5"),
+	}})
+	matches := h.MustRun(testProfile)
+	assert.Len(t, matches, 2)
+	for _, match := range matches {
+		assert.Len(t, match.Tickets, mmlogic.Simple1vs1PlayersPerMatch)
+		assert.True(t, match.AllocateGameserver)
+	}
+	mftest.AssertMatchedTickets(t, matches, "t1", "t2", "t3", "t4")
+	h.AssertValidProposals(testProfile, matches, mmlogic.Simple1vs1PlayersPerMatch)
+}
+
+func TestRunWithoutTickets(t *testing.T) {
+	h := newHarness(t, nil)
+	assert.Empty(t, h.MustRun(testProfile))
+}
+
+func TestRunQueryError(t *testing.T) {
+	h := newHarness(t, nil)
+	h.Query.FailTickets("test-pool", status.Error(codes.Unavailable, "query service is down"))
+	_, err := h.Run(context.Background(), testProfile)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,108 @@
+package omutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// AssignmentFailuresError is returned when Open Match could not assign some of the tickets,
+// e.g. because they were deleted while the match was being made.
+type AssignmentFailuresError struct {
+	Failures []*pb.AssignmentFailure
+}
+
+func (e *AssignmentFailuresError) Error() string {
+	var failures []string
+	for _, f := range e.Failures {
+		failures = append(failures, fmt.Sprintf("%s (%s)", f.TicketId, f.Cause))
+	}
+	return fmt.Sprintf("failed to assign %d tickets: %s", len(e.Failures), strings.Join(failures, ", "))
+}
+
+// FailedGroups returns a group for each game server (connection) of which no ticket was assigned,
+// with the tickets of all its groups. Nobody will join these game servers, so they should be released.
+func (e *AssignmentFailuresError) FailedGroups(groups []*pb.AssignmentGroup) []*pb.AssignmentGroup {
+	failed := map[string]struct{}{}
+	for _, f := range e.Failures {
+		failed[f.TicketId] = struct{}{}
+	}
+	var connections []string
+	byConnection := map[string]*pb.AssignmentGroup{}
+	allFailed := map[string]bool{}
+	for _, group := range groups {
+		conn := group.Assignment.GetConnection()
+		merged, ok := byConnection[conn]
+		if !ok {
+			merged = &pb.AssignmentGroup{Assignment: group.Assignment}
+			byConnection[conn] = merged
+			connections = append(connections, conn)
+			allFailed[conn] = true
+		}
+		for _, id := range group.TicketIds {
+			merged.TicketIds = append(merged.TicketIds, id)
+			if _, ok := failed[id]; !ok {
+				allFailed[conn] = false
+			}
+		}
+	}
+	var failedGroups []*pb.AssignmentGroup
+	for _, conn := range connections {
+		if allFailed[conn] && len(byConnection[conn].TicketIds) > 0 {
+			failedGroups = append(failedGroups, byConnection[conn])
+		}
+	}
+	return failedGroups
+}
+
+// AssignTickets assigns the groups and returns the per-ticket failures in the response as *AssignmentFailuresError.
+func AssignTickets(ctx context.Context, backend pb.BackendServiceClient, groups []*pb.AssignmentGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	resp, err := backend.AssignTickets(ctx, &pb.AssignTicketsRequest{Assignments: groups})
+	if err != nil {
+		return fmt.Errorf("failed to assign tickets: %w", err)
+	}
+	if len(resp.Failures) > 0 {
+		return &AssignmentFailuresError{Failures: resp.Failures}
+	}
+	return nil
+}
+
+// AllocationError is returned by an Assigner, along with the assignments of the other matches,
+// when no game server could be allocated for some of the matches.
+type AllocationError struct {
+	Matches []*pb.Match
+	Err     error
+}
+
+func (e *AllocationError) Error() string {
+	return fmt.Sprintf("failed to allocate game servers for %d matches: %v", len(e.Matches), e.Err)
+}
+
+func (e *AllocationError) Unwrap() error {
+	return e.Err
+}
+
+// ReleaseMatch returns the tickets of the match to the pool without waiting for the pending timeout,
+// and deletes the backfill created for the game server of the match.
+// frontend can be nil to leave the backfill until it expires.
+func ReleaseMatch(ctx context.Context, backend pb.BackendServiceClient, frontend pb.FrontendServiceClient, match *pb.Match) error {
+	if _, err := backend.ReleaseTickets(ctx, &pb.ReleaseTicketsRequest{TicketIds: ticketIDs(match)}); err != nil {
+		return fmt.Errorf("failed to release tickets: %w", err)
+	}
+	// Open Match has created the new backfill of the match on FetchMatches,
+	// but there is no game server to acknowledge it.
+	if frontend == nil || !match.AllocateGameserver || match.Backfill.GetId() == "" {
+		return nil
+	}
+	if _, err := frontend.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: match.Backfill.Id}); err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to delete backfill: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package omutils
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// Teams is the number of teams the players of a match are split into.
+const Teams = 2
+
+const (
+	regionKey    = "region"
+	matchIDKey   = "matchId"
+	teamKey      = "team"
+	joinTokenKey = "joinToken"
+)
+
+// AssignmentInfo is the metadata of the game server for a player in the extensions of an Assignment.
+type AssignmentInfo struct {
+	Region  string
+	MatchID string
+	Team    int32
+	// JoinToken is signed by JoinTokenSigner and verified by the game server.
+	JoinToken string
+}
+
+func SetAssignmentInfo(as *pb.Assignment, info *AssignmentInfo) error {
+	if as.Extensions == nil {
+		as.Extensions = map[string]*anypb.Any{}
+	}
+	values := map[string]proto.Message{
+		regionKey:    wrapperspb.String(info.Region),
+		matchIDKey:   wrapperspb.String(info.MatchID),
+		teamKey:      wrapperspb.Int32(info.Team),
+		joinTokenKey: wrapperspb.String(info.JoinToken),
+	}
+	for key, v := range values {
+		any, err := anypb.New(v)
+		if err != nil {
+			return err
+		}
+		as.Extensions[key] = any
+	}
+	return nil
+}
+
+// GetAssignmentInfo returns the metadata in the extensions. The missing fields are empty,
+// e.g. for an Assignment conveyed by a backfill.
+func GetAssignmentInfo(as *pb.Assignment) (*AssignmentInfo, error) {
+	info := &AssignmentInfo{}
+	stringFields := map[string]*string{regionKey: &info.Region, matchIDKey: &info.MatchID, joinTokenKey: &info.JoinToken}
+	for key, dst := range stringFields {
+		if any, ok := as.GetExtensions()[key]; ok {
+			var val wrapperspb.StringValue
+			if err := any.UnmarshalTo(&val); err != nil {
+				return nil, fmt.Errorf("failed to get %s extension: %w", key, err)
+			}
+			*dst = val.Value
+		}
+	}
+	if any, ok := as.GetExtensions()[teamKey]; ok {
+		var val wrapperspb.Int32Value
+		if err := any.UnmarshalTo(&val); err != nil {
+			return nil, fmt.Errorf("failed to get %s extension: %w", teamKey, err)
+		}
+		info.Team = val.Value
+	}
+	return info, nil
+}
+
+// MatchRegion returns the "region" string arg shared by the tickets of the match, or empty if they differ.
+func MatchRegion(match *pb.Match) string {
+	region := ""
+	for i, ticket := range match.Tickets {
+		r := ticket.GetSearchFields().GetStringArgs()[regionKey]
+		if i > 0 && r != region {
+			return ""
+		}
+		region = r
+	}
+	return region
+}
+
+// AssignMatch returns an AssignmentGroup for each player of the match on the game server of the connection,
+// with the region, the match ID, the team and a join token signed for the player.
+func AssignMatch(match *pb.Match, connection string, signer *JoinTokenSigner) ([]*pb.AssignmentGroup, error) {
+	region := MatchRegion(match)
+	var groups []*pb.AssignmentGroup
+	for i, ticket := range match.Tickets {
+		token, err := signer.Sign(JoinClaims{Connection: connection, TicketID: ticket.Id, MatchID: match.MatchId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign join token: %w", err)
+		}
+		as := &pb.Assignment{Connection: connection}
+		if err := SetAssignmentInfo(as, &AssignmentInfo{
+			Region:    region,
+			MatchID:   match.MatchId,
+			Team:      int32(i % Teams),
+			JoinToken: token,
+		}); err != nil {
+			return nil, err
+		}
+		groups = append(groups, &pb.AssignmentGroup{TicketIds: []string{ticket.Id}, Assignment: as})
+	}
+	return groups, nil
+}
@@ -0,0 +1,64 @@
+package omutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestAssignMatch(t *testing.T) {
+	signer := NewJoinTokenSigner([]byte("secret"))
+	match := &pb.Match{MatchId: "match-1"}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		match.Tickets = append(match.Tickets, &pb.Ticket{Id: id, SearchFields: &pb.SearchFields{StringArgs: map[string]string{"region": "asia"}}})
+	}
+	groups, err := AssignMatch(match, "gs-1", signer)
+	assert.NoError(t, err)
+	if !assert.Len(t, groups, 3) {
+		return
+	}
+	for i, group := range groups {
+		assert.Equal(t, []string{match.Tickets[i].Id}, group.TicketIds)
+		assert.Equal(t, "gs-1", group.Assignment.Connection)
+		info, err := GetAssignmentInfo(group.Assignment)
+		assert.NoError(t, err)
+		assert.Equal(t, "asia", info.Region)
+		assert.Equal(t, "match-1", info.MatchID)
+		assert.Equal(t, int32(i%Teams), info.Team)
+		claims, err := signer.Verify(info.JoinToken)
+		assert.NoError(t, err)
+		assert.Equal(t, match.Tickets[i].Id, claims.TicketID)
+		assert.Equal(t, "gs-1", claims.Connection)
+	}
+
+	// An Assignment without extensions, e.g. conveyed by a backfill.
+	info, err := GetAssignmentInfo(&pb.Assignment{Connection: "gs-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, &AssignmentInfo{}, info)
+}
+
+func TestMatchRegion(t *testing.T) {
+	ticket := func(region string) *pb.Ticket {
+		return &pb.Ticket{SearchFields: &pb.SearchFields{StringArgs: map[string]string{"region": region}}}
+	}
+	assert.Equal(t, "eu", MatchRegion(&pb.Match{Tickets: []*pb.Ticket{ticket("eu"), ticket("eu")}}))
+	assert.Equal(t, "", MatchRegion(&pb.Match{Tickets: []*pb.Ticket{ticket("eu"), ticket("us")}}))
+	assert.Equal(t, "", MatchRegion(&pb.Match{Tickets: []*pb.Ticket{{Id: "t1"}}}))
+}
+
+func TestFailedGroupsOfPlayers(t *testing.T) {
+	groups := []*pb.AssignmentGroup{
+		{TicketIds: []string{"t1"}, Assignment: &pb.Assignment{Connection: "gs-1"}},
+		{TicketIds: []string{"t2"}, Assignment: &pb.Assignment{Connection: "gs-1"}},
+		{TicketIds: []string{"t3"}, Assignment: &pb.Assignment{Connection: "gs-2"}},
+		{TicketIds: []string{"t4"}, Assignment: &pb.Assignment{Connection: "gs-2"}},
+	}
+	e := &AssignmentFailuresError{Failures: []*pb.AssignmentFailure{{TicketId: "t1"}, {TicketId: "t3"}, {TicketId: "t4"}}}
+	failed := e.FailedGroups(groups)
+	// gs-1 still has t2.
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "gs-2", failed[0].Assignment.Connection)
+		assert.Equal(t, []string{"t3", "t4"}, failed[0].TicketIds)
+	}
+}
@@ -0,0 +1,133 @@
+package omutils
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	defaultMaxBackoff       = 1 * time.Minute
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 30 * time.Second
+)
+
+// CircuitState is the state of the circuit breaker of a profile.
+type CircuitState string
+
+const (
+	// CircuitClosed runs the profile every interval, or with backoff after failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen pauses the profile after repeated failures.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen runs the profile once after the pause to check whether it has recovered.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// ProfileStatus is the health of the director for a profile.
+type ProfileStatus struct {
+	Profile             string        `json:"profile"`
+	State               CircuitState  `json:"state"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	LastSuccess         time.Time     `json:"lastSuccess"`
+	LastError           string        `json:"lastError,omitempty"`
+	LastErrorAt         time.Time     `json:"lastErrorAt"`
+	NextRun             time.Time     `json:"nextRun"`
+	Stats               DirectorStats `json:"stats"`
+}
+
+// circuitBreaker decides the delay before the next run from the consecutive failures.
+type circuitBreaker struct {
+	interval         time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	openDuration     time.Duration
+	// jitter returns a random number in [0, 1).
+	jitter func() float64
+
+	state               CircuitState
+	consecutiveFailures int
+	lastSuccess         time.Time
+	lastError           error
+	lastErrorAt         time.Time
+	nextRun             time.Time
+}
+
+// record updates the state with the result of a run and returns the delay before the next run.
+func (b *circuitBreaker) record(now time.Time, err error) time.Duration {
+	if err == nil {
+		b.state = CircuitClosed
+		b.consecutiveFailures = 0
+		b.lastSuccess = now
+		return b.schedule(now, b.interval)
+	}
+	b.consecutiveFailures++
+	b.lastError = err
+	b.lastErrorAt = now
+	if b.failureThreshold > 0 && b.consecutiveFailures >= b.failureThreshold {
+		b.state = CircuitOpen
+		return b.schedule(now, b.openDuration)
+	}
+	return b.schedule(now, b.backoff())
+}
+
+// halfOpen is called when the pause is over and the next run is a trial.
+func (b *circuitBreaker) halfOpen() {
+	if b.state == CircuitOpen {
+		b.state = CircuitHalfOpen
+	}
+}
+
+// backoff is the exponential backoff from the interval, randomized in [d/2, d) so that the profiles don't retry in lockstep.
+func (b *circuitBreaker) backoff() time.Duration {
+	d := b.interval
+	for i := 0; i < b.consecutiveFailures && d < b.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > b.maxBackoff {
+		d = b.maxBackoff
+	}
+	return d/2 + time.Duration(b.jitter()*float64(d/2))
+}
+
+func (b *circuitBreaker) schedule(now time.Time, delay time.Duration) time.Duration {
+	b.nextRun = now.Add(delay)
+	return delay
+}
+
+// isBreakerFailure reports whether the error is caused by the backend or the Match Function.
+// Tickets that fail to be assigned and a shortage of game servers are not failures of the profile.
+func isBreakerFailure(err error) bool {
+	var failures *AssignmentFailuresError
+	var allocErr *AllocationError
+	return err != nil && !errors.As(err, &failures) && !errors.As(err, &allocErr)
+}
+
+func newCircuitBreaker(o *directorOptions) *circuitBreaker {
+	return &circuitBreaker{
+		maxBackoff:       o.maxBackoff,
+		failureThreshold: o.failureThreshold,
+		openDuration:     o.openDuration,
+		jitter:           rand.Float64,
+		state:            CircuitClosed,
+	}
+}
+
+// statusOf is the status with the copied stats.
+func statusOf(profile *pb.MatchProfile, b *circuitBreaker, stats DirectorStats) ProfileStatus {
+	status := ProfileStatus{
+		Profile:             profile.Name,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		LastSuccess:         b.lastSuccess,
+		LastErrorAt:         b.lastErrorAt,
+		NextRun:             b.nextRun,
+		Stats:               stats,
+	}
+	if b.lastError != nil {
+		status.LastError = b.lastError.Error()
+	}
+	return status
+}
@@ -0,0 +1,75 @@
+package omutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func newTestBreaker() *circuitBreaker {
+	b := newCircuitBreaker(&directorOptions{maxBackoff: 8 * time.Second, failureThreshold: 4, openDuration: 30 * time.Second})
+	b.interval = 1 * time.Second
+	// Always the upper limit of the randomized backoff.
+	b.jitter = func() float64 { return 1 }
+	return b
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newTestBreaker()
+	now := time.Now()
+	errMF := errors.New("match function is down")
+
+	assert.Equal(t, 2*time.Second, b.record(now, errMF))
+	assert.Equal(t, 4*time.Second, b.record(now, errMF))
+	assert.Equal(t, 8*time.Second, b.record(now, errMF))
+	assert.Equal(t, CircuitClosed, b.state)
+
+	// The profile is paused after 4 consecutive failures.
+	assert.Equal(t, 30*time.Second, b.record(now, errMF))
+	assert.Equal(t, CircuitOpen, b.state)
+	assert.Equal(t, now.Add(30*time.Second), b.nextRun)
+
+	// The trial after the pause fails, so the profile is paused again.
+	b.halfOpen()
+	assert.Equal(t, CircuitHalfOpen, b.state)
+	assert.Equal(t, 30*time.Second, b.record(now, errMF))
+	assert.Equal(t, CircuitOpen, b.state)
+
+	b.halfOpen()
+	assert.Equal(t, 1*time.Second, b.record(now, nil))
+	assert.Equal(t, CircuitClosed, b.state)
+	assert.Equal(t, 0, b.consecutiveFailures)
+	assert.Equal(t, now, b.lastSuccess)
+	assert.Equal(t, errMF, b.lastError)
+}
+
+func TestCircuitBreakerJitter(t *testing.T) {
+	b := newTestBreaker()
+	b.jitter = func() float64 { return 0 }
+	assert.Equal(t, 1*time.Second, b.record(time.Now(), errors.New("backend is down")))
+}
+
+func TestDirectorStatus(t *testing.T) {
+	d := NewDirector(&fakeBackend{}, &pb.MatchProfile{Name: "test-profile"}, &pb.FunctionConfig{}, &fakeAssigner{}, WithCircuitBreaker(1, time.Minute))
+	d.breaker.interval = time.Second
+	// Assignment failures don't open the circuit.
+	d.recordRun(&AssignmentFailuresError{Failures: []*pb.AssignmentFailure{{TicketId: "t1"}}})
+	assert.Equal(t, CircuitClosed, d.Status().State)
+	d.recordRun(errors.New("failed to fetch matches: match function is down"))
+
+	rec := httptest.NewRecorder()
+	DirectorStatusHandler(d).ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	var statuses []ProfileStatus
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, "test-profile", statuses[0].Profile)
+		assert.Equal(t, CircuitOpen, statuses[0].State)
+		assert.Equal(t, 1, statuses[0].ConsecutiveFailures)
+		assert.Equal(t, "failed to fetch matches: match function is down", statuses[0].LastError)
+	}
+}
@@ -2,44 +2,384 @@ package omutils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/bojand/hri"
-	"github.com/castaneai/omtools"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
 	"open-match.dev/open-match/pkg/pb"
 )
 
-func NewTestDirector(backendAddr string, profile *pb.MatchProfile, matchfunction string) (*omtools.Director, error) {
-	backend, err := NewOMBackendClient(backendAddr)
+var directorLogger = logging.Component("director")
+
+// MatchObserver is notified of each match the director assigns and its assignment,
+// e.g. to evaluate the quality or to record the traffic.
+type MatchObserver func(match *pb.Match, assignment *pb.Assignment)
+
+// Assigner makes the assignments of the matches, e.g. by allocating game servers.
+type Assigner interface {
+	Assign(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error)
+}
+
+// Deallocator is optionally implemented by an Assigner to release the game server of a group
+// when none of its tickets could be assigned.
+type Deallocator interface {
+	Deallocate(ctx context.Context, group *pb.AssignmentGroup) error
+}
+
+// DirectorStats is the cumulative result of the director.
+type DirectorStats struct {
+	Matches         int `json:"matches"`
+	AssignedTickets int `json:"assignedTickets"`
+	// AssignmentFailures is the number of tickets that failed to be assigned by the cause.
+	AssignmentFailures map[pb.AssignmentFailure_Cause]int `json:"assignmentFailures"`
+	Deallocations      int                                `json:"deallocations"`
+	// AllocationFailures is the number of matches released because no game server was allocated.
+	AllocationFailures int `json:"allocationFailures"`
+	ReleasedTickets    int `json:"releasedTickets"`
+}
+
+// Director fetches matches of a profile periodically and assigns them.
+type Director struct {
+	backend   pb.BackendServiceClient
+	profile   *pb.MatchProfile
+	fc        *pb.FunctionConfig
+	frontend  pb.FrontendServiceClient
+	assigner  Assigner
+	observers []MatchObserver
+	logger    *slog.Logger
+	// allocationRetries is the retry budget to allocate game servers for a match.
+	allocationRetries int
+
+	mu      sync.Mutex
+	stats   DirectorStats
+	breaker *circuitBreaker
+}
+
+func NewDirector(backend pb.BackendServiceClient, profile *pb.MatchProfile, fc *pb.FunctionConfig, assigner Assigner, opts ...DirectorOption) *Director {
+	o := &directorOptions{
+		allocationRetries: 1,
+		maxBackoff:        defaultMaxBackoff,
+		failureThreshold:  defaultFailureThreshold,
+		openDuration:      defaultOpenDuration,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Director{
+		backend:           backend,
+		profile:           profile,
+		fc:                fc,
+		frontend:          o.frontend,
+		assigner:          assigner,
+		observers:         o.observers,
+		logger:            directorLogger.With(logging.Profile(profile.Name)),
+		allocationRetries: o.allocationRetries,
+		stats:             DirectorStats{AssignmentFailures: map[pb.AssignmentFailure_Cause]int{}},
+		breaker:           newCircuitBreaker(o),
+	}
+}
+
+// NewTestDirector returns a director of the match function in the local cluster
+// that assigns dummy game servers, connecting to the backend at backendAddr.
+func NewTestDirector(backendAddr string, profile *pb.MatchProfile, matchfunction string, opts ...DirectorOption) (*Director, error) {
+	o := &directorOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	backend, err := NewOMBackendClient(backendAddr, o.clientOptions...)
 	if err != nil {
 		return nil, err
 	}
-	return omtools.NewDirector(backend, profile, &pb.FunctionConfig{
+	signer, err := NewRandomJoinTokenSigner()
+	if err != nil {
+		return nil, err
+	}
+	return NewDirector(backend, profile, &pb.FunctionConfig{
 		Host: fmt.Sprintf("%s.open-match.svc.cluster.local.", matchfunction),
 		Port: 50502,
 		Type: pb.FunctionConfig_GRPC,
-	}, assignFunc(dummyAssign)), nil
+	}, dummyAssigner(signer), opts...), nil
 }
 
-type assignFunc func(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error)
+// AssignFunc is an Assigner as a function.
+type AssignFunc func(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error)
 
-func (f assignFunc) Assign(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
+func (f AssignFunc) Assign(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
 	return f(ctx, matches)
 }
 
-func dummyAssign(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
-	var asgs []*pb.AssignmentGroup
+// Run runs RunOnce every interval until ctx is done.
+// After a failure of the backend or the Match Function, the next run is delayed with exponential backoff,
+// and the profile is paused by the circuit breaker after repeated failures.
+func (d *Director) Run(ctx context.Context, interval time.Duration) error {
+	d.mu.Lock()
+	d.breaker.interval = interval
+	timer := time.NewTimer(d.breaker.schedule(time.Now(), interval))
+	d.mu.Unlock()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		d.mu.Lock()
+		d.breaker.halfOpen()
+		d.mu.Unlock()
+		err := d.RunOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		timer.Reset(d.recordRun(err))
+	}
+}
+
+// recordRun updates the circuit breaker with the result of RunOnce and returns the delay before the next run.
+func (d *Director) recordRun(err error) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b := d.breaker
+	prev := b.state
+	if !isBreakerFailure(err) {
+		delay := b.record(time.Now(), nil)
+		if prev != CircuitClosed {
+			d.logger.Info("profile recovered; circuit closed")
+		}
+		return delay
+	}
+	delay := b.record(time.Now(), err)
+	switch {
+	case b.state != CircuitOpen:
+		d.logger.Warn("failed to run director; backing off", "error", err, "consecutive_failures", b.consecutiveFailures, "backoff", delay)
+	case prev == CircuitHalfOpen:
+		d.logger.Warn("profile is still failing; circuit re-opened", "error", err, "pause", delay)
+	default:
+		d.logger.Error("too many failures; circuit opened", "error", err, "consecutive_failures", b.consecutiveFailures, "pause", delay)
+	}
+	return delay
+}
+
+// RunOnce fetches the matches and assigns them.
+// The matches without game servers, or all the matches if the assigner fails otherwise,
+// are returned as *AllocationError after their tickets are released, and the tickets that Open Match failed to assign are returned as *AssignmentFailuresError
+// after the game servers of the groups without any assigned ticket are deallocated.
+func (d *Director) RunOnce(ctx context.Context) error {
+	matches, err := d.fetchMatches(ctx)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	groups, allocErr := d.allocate(ctx, matches)
+	assignErr := AssignTickets(ctx, d.backend, groups)
+	var failures *AssignmentFailuresError
+	if assignErr != nil && !errors.As(assignErr, &failures) {
+		return errors.Join(allocErr, assignErr)
+	}
+
+	failed := map[string]struct{}{}
+	if failures != nil {
+		for _, f := range failures.Failures {
+			failed[f.TicketId] = struct{}{}
+			d.logger.Warn("failed to assign ticket", logging.TicketID(f.TicketId), "cause", f.Cause.String())
+		}
+		for _, group := range failures.FailedGroups(groups) {
+			d.deallocate(ctx, group)
+		}
+	}
+
+	matchByTicket := map[string]*pb.Match{}
 	for _, match := range matches {
+		for _, ticket := range match.Tickets {
+			matchByTicket[ticket.Id] = match
+		}
+	}
+	// Each match is observed once with the assignment of one of its players.
+	observed := map[*pb.Match]struct{}{}
+	assigned := 0
+	for _, group := range groups {
+		for _, id := range group.TicketIds {
+			if _, ok := failed[id]; ok {
+				continue
+			}
+			assigned++
+			match, ok := matchByTicket[id]
+			if !ok {
+				continue
+			}
+			if _, ok := observed[match]; ok {
+				continue
+			}
+			observed[match] = struct{}{}
+			for _, observe := range d.observers {
+				observe(match, group.Assignment)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.stats.Matches += len(matches)
+	d.stats.AssignedTickets += assigned
+	if failures != nil {
+		for _, f := range failures.Failures {
+			d.stats.AssignmentFailures[f.Cause]++
+		}
+	}
+	d.mu.Unlock()
+	return errors.Join(allocErr, assignErr)
+}
+
+// allocate assigns the matches, retrying the matches without game servers within the retry budget.
+// The tickets of the matches that are still without game servers are released, and returned as *AllocationError
+// along with the assignments of the other matches. An error of the assigner other than *AllocationError
+// leaves every match without an assignment group, so all of them are released.
+func (d *Director) allocate(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
+	groups, err := d.assigner.Assign(ctx, matches)
+	var allocErr *AllocationError
+	for retry := 0; retry < d.allocationRetries && errors.As(err, &allocErr); retry++ {
+		d.logger.Warn("failed to allocate game servers; retrying", "error", allocErr.Err, "matches", len(allocErr.Matches), "retry", retry+1)
+		var retried []*pb.AssignmentGroup
+		retried, err = d.assigner.Assign(ctx, allocErr.Matches)
+		groups = append(groups, retried...)
+	}
+	if err == nil {
+		return groups, nil
+	}
+	if !errors.As(err, &allocErr) {
+		allocErr = &AllocationError{Matches: unassignedMatches(matches, groups), Err: err}
+	}
+	for _, match := range allocErr.Matches {
 		tids := ticketIDs(match)
-		conn := hri.Random()
-		log.Printf("assign '%s' to tickets: %v", conn, tids)
-		asgs = append(asgs, &pb.AssignmentGroup{
-			TicketIds:  tids,
-			Assignment: &pb.Assignment{Connection: conn},
-		})
-	}
-	return asgs, nil
+		if rerr := ReleaseMatch(ctx, d.backend, d.frontend, match); rerr != nil {
+			d.logger.Error("failed to release match", logging.MatchID(match.MatchId), logging.TicketIDs(tids), "error", rerr)
+			continue
+		}
+		d.logger.Warn("released match without game server", logging.MatchID(match.MatchId), logging.TicketIDs(tids), "error", allocErr.Err)
+		d.mu.Lock()
+		d.stats.AllocationFailures++
+		d.stats.ReleasedTickets += len(tids)
+		d.mu.Unlock()
+	}
+	return groups, allocErr
+}
+
+// unassignedMatches returns the matches none of whose tickets are in the groups.
+func unassignedMatches(matches []*pb.Match, groups []*pb.AssignmentGroup) []*pb.Match {
+	assigned := map[string]struct{}{}
+	for _, group := range groups {
+		for _, id := range group.TicketIds {
+			assigned[id] = struct{}{}
+		}
+	}
+	var unassigned []*pb.Match
+	for _, match := range matches {
+		found := false
+		for _, ticket := range match.Tickets {
+			if _, ok := assigned[ticket.Id]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unassigned = append(unassigned, match)
+		}
+	}
+	return unassigned
+}
+
+func (d *Director) deallocate(ctx context.Context, group *pb.AssignmentGroup) {
+	dealloc, ok := d.assigner.(Deallocator)
+	if !ok {
+		return
+	}
+	if err := dealloc.Deallocate(ctx, group); err != nil {
+		d.logger.Error("failed to deallocate game server", logging.TicketIDs(group.TicketIds), "connection", group.Assignment.GetConnection(), "error", err)
+		return
+	}
+	d.logger.Info("deallocated game server", logging.TicketIDs(group.TicketIds), "connection", group.Assignment.GetConnection())
+	d.mu.Lock()
+	d.stats.Deallocations++
+	d.mu.Unlock()
+}
+
+// Stats returns a copy of the cumulative result.
+func (d *Director) Stats() DirectorStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.copyStats()
+}
+
+// Status returns the health of the profile, e.g. to find a broken Match Function.
+func (d *Director) Status() ProfileStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return statusOf(d.profile, d.breaker, d.copyStats())
+}
+
+// DirectorStatusHandler serves the status of the directors in JSON.
+func DirectorStatusHandler(directors ...*Director) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := []ProfileStatus{}
+		for _, d := range directors {
+			statuses = append(statuses, d.Status())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(statuses)
+	})
+}
+
+func (d *Director) copyStats() DirectorStats {
+	stats := d.stats
+	stats.AssignmentFailures = map[pb.AssignmentFailure_Cause]int{}
+	for cause, n := range d.stats.AssignmentFailures {
+		stats.AssignmentFailures[cause] = n
+	}
+	return stats
+}
+
+func (d *Director) fetchMatches(ctx context.Context) ([]*pb.Match, error) {
+	stream, err := d.backend.FetchMatches(ctx, &pb.FetchMatchesRequest{Config: d.fc, Profile: d.profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch matches: %w", err)
+	}
+	var matches []*pb.Match
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch matches: %w", err)
+		}
+		matches = append(matches, resp.Match)
+	}
+}
+
+// dummyAssigner assigns a game server with a random name for each match.
+func dummyAssigner(signer *JoinTokenSigner) AssignFunc {
+	return func(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
+		var asgs []*pb.AssignmentGroup
+		for _, match := range matches {
+			conn := hri.Random()
+			directorLogger.Info("assign", logging.MatchID(match.MatchId), logging.TicketIDs(ticketIDs(match)), "connection", conn)
+			groups, err := AssignMatch(match, conn, signer)
+			if err != nil {
+				return nil, err
+			}
+			asgs = append(asgs, groups...)
+		}
+		return asgs, nil
+	}
 }
 
 func ticketIDs(match *pb.Match) []string {
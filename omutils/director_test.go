@@ -0,0 +1,212 @@
+package omutils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// fakeBackend returns the matches on FetchMatches and the failures on AssignTickets.
+type fakeBackend struct {
+	pb.BackendServiceClient
+	matches  []*pb.Match
+	failures []*pb.AssignmentFailure
+	assigned []*pb.AssignmentGroup
+	released []string
+}
+
+func (f *fakeBackend) FetchMatches(ctx context.Context, in *pb.FetchMatchesRequest, opts ...grpc.CallOption) (pb.BackendService_FetchMatchesClient, error) {
+	return &fakeFetchMatchesStream{matches: f.matches}, nil
+}
+
+func (f *fakeBackend) AssignTickets(ctx context.Context, in *pb.AssignTicketsRequest, opts ...grpc.CallOption) (*pb.AssignTicketsResponse, error) {
+	f.assigned = append(f.assigned, in.Assignments...)
+	return &pb.AssignTicketsResponse{Failures: f.failures}, nil
+}
+
+func (f *fakeBackend) ReleaseTickets(ctx context.Context, in *pb.ReleaseTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseTicketsResponse, error) {
+	f.released = append(f.released, in.TicketIds...)
+	return &pb.ReleaseTicketsResponse{}, nil
+}
+
+// fakeBackfillFrontend records the deleted backfills.
+type fakeBackfillFrontend struct {
+	pb.FrontendServiceClient
+	deleted []string
+}
+
+func (f *fakeBackfillFrontend) DeleteBackfill(ctx context.Context, in *pb.DeleteBackfillRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	f.deleted = append(f.deleted, in.BackfillId)
+	return &emptypb.Empty{}, nil
+}
+
+type fakeFetchMatchesStream struct {
+	grpc.ClientStream
+	matches []*pb.Match
+}
+
+func (s *fakeFetchMatchesStream) Recv() (*pb.FetchMatchesResponse, error) {
+	if len(s.matches) == 0 {
+		return nil, io.EOF
+	}
+	match := s.matches[0]
+	s.matches = s.matches[1:]
+	return &pb.FetchMatchesResponse{Match: match}, nil
+}
+
+// fakeAssigner assigns a game server named after the match and records the deallocated ones.
+type fakeAssigner struct {
+	deallocated []string
+	// allocationFailures is the number of times to fail to allocate a game server for the match ID.
+	allocationFailures map[string]int
+	// errs are returned by the successive calls instead of the assignments (nil to assign as usual).
+	errs []error
+}
+
+func (a *fakeAssigner) Assign(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
+	if len(a.errs) > 0 {
+		err := a.errs[0]
+		a.errs = a.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	var groups []*pb.AssignmentGroup
+	var unallocated []*pb.Match
+	for _, match := range matches {
+		if a.allocationFailures[match.MatchId] > 0 {
+			a.allocationFailures[match.MatchId]--
+			unallocated = append(unallocated, match)
+			continue
+		}
+		groups = append(groups, &pb.AssignmentGroup{TicketIds: ticketIDs(match), Assignment: &pb.Assignment{Connection: match.MatchId}})
+	}
+	if len(unallocated) > 0 {
+		return groups, &AllocationError{Matches: unallocated, Err: errors.New("no game server available")}
+	}
+	return groups, nil
+}
+
+func (a *fakeAssigner) Deallocate(ctx context.Context, group *pb.AssignmentGroup) error {
+	a.deallocated = append(a.deallocated, group.Assignment.Connection)
+	return nil
+}
+
+func newMatch(id string, ticketIDs ...string) *pb.Match {
+	match := &pb.Match{MatchId: id}
+	for _, tid := range ticketIDs {
+		match.Tickets = append(match.Tickets, &pb.Ticket{Id: tid})
+	}
+	return match
+}
+
+func TestDirectorAssignmentFailures(t *testing.T) {
+	backend := &fakeBackend{
+		matches: []*pb.Match{newMatch("match-1", "t1", "t2"), newMatch("match-2", "t3", "t4"), newMatch("match-3", "t5")},
+		failures: []*pb.AssignmentFailure{
+			{TicketId: "t2", Cause: pb.AssignmentFailure_TICKET_NOT_FOUND},
+			{TicketId: "t3", Cause: pb.AssignmentFailure_TICKET_NOT_FOUND},
+			{TicketId: "t4", Cause: pb.AssignmentFailure_TICKET_NOT_FOUND},
+		},
+	}
+	assigner := &fakeAssigner{}
+	var observed []string
+	d := NewDirector(backend, &pb.MatchProfile{Name: "test-profile"}, &pb.FunctionConfig{}, assigner, WithObservers(func(match *pb.Match, _ *pb.Assignment) {
+		observed = append(observed, match.MatchId)
+	}))
+
+	err := d.RunOnce(context.Background())
+	var failures *AssignmentFailuresError
+	if assert.True(t, errors.As(err, &failures)) {
+		assert.Len(t, failures.Failures, 3)
+	}
+	assert.Len(t, backend.assigned, 3)
+	// Nobody joins match-2, so its game server is released. match-1 still has t1.
+	assert.Equal(t, []string{"match-2"}, assigner.deallocated)
+	assert.Equal(t, []string{"match-1", "match-3"}, observed)
+
+	stats := d.Stats()
+	assert.Equal(t, 3, stats.Matches)
+	assert.Equal(t, 2, stats.AssignedTickets)
+	assert.Equal(t, 3, stats.AssignmentFailures[pb.AssignmentFailure_TICKET_NOT_FOUND])
+	assert.Equal(t, 1, stats.Deallocations)
+}
+
+func TestDirectorWithoutFailures(t *testing.T) {
+	backend := &fakeBackend{matches: []*pb.Match{newMatch("match-1", "t1", "t2")}}
+	assigner := &fakeAssigner{}
+	d := NewDirector(backend, &pb.MatchProfile{Name: "test-profile"}, &pb.FunctionConfig{}, assigner)
+	assert.NoError(t, d.RunOnce(context.Background()))
+	assert.Empty(t, assigner.deallocated)
+	assert.Equal(t, 2, d.Stats().AssignedTickets)
+}
+
+func TestDirectorAllocationFailures(t *testing.T) {
+	newBackfillMatch := newMatch("match-3", "t5")
+	newBackfillMatch.AllocateGameserver = true
+	newBackfillMatch.Backfill = &pb.Backfill{Id: "backfill-3"}
+	backend := &fakeBackend{matches: []*pb.Match{newMatch("match-1", "t1", "t2"), newMatch("match-2", "t3", "t4"), newBackfillMatch}}
+	frontend := &fakeBackfillFrontend{}
+	// match-2 gets a game server on the retry, but match-3 runs out of the retry budget.
+	assigner := &fakeAssigner{allocationFailures: map[string]int{"match-2": 1, "match-3": 2}}
+	d := NewDirector(backend, &pb.MatchProfile{Name: "test-profile"}, &pb.FunctionConfig{}, assigner, WithFrontend(frontend), WithAllocationRetries(1))
+
+	err := d.RunOnce(context.Background())
+	var allocErr *AllocationError
+	if assert.True(t, errors.As(err, &allocErr)) {
+		assert.Len(t, allocErr.Matches, 1)
+		assert.Equal(t, "match-3", allocErr.Matches[0].MatchId)
+	}
+	assert.False(t, isBreakerFailure(err))
+	assert.Len(t, backend.assigned, 2)
+	assert.Equal(t, []string{"t5"}, backend.released)
+	assert.Equal(t, []string{"backfill-3"}, frontend.deleted)
+
+	stats := d.Stats()
+	assert.Equal(t, 4, stats.AssignedTickets)
+	assert.Equal(t, 1, stats.AllocationFailures)
+	assert.Equal(t, 1, stats.ReleasedTickets)
+}
+
+func TestDirectorAssignerErrors(t *testing.T) {
+	signErr := errors.New("failed to sign join token")
+	tests := []struct {
+		name     string
+		assigner *fakeAssigner
+		assigned int
+		released []string
+	}{
+		{
+			name:     "first assign",
+			assigner: &fakeAssigner{errs: []error{signErr}},
+			released: []string{"t1", "t2", "t3", "t4"},
+		},
+		{
+			name:     "retry",
+			assigner: &fakeAssigner{allocationFailures: map[string]int{"match-2": 1}, errs: []error{nil, signErr}},
+			assigned: 1,
+			released: []string{"t3", "t4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{matches: []*pb.Match{newMatch("match-1", "t1", "t2"), newMatch("match-2", "t3", "t4")}}
+			d := NewDirector(backend, &pb.MatchProfile{Name: "test-profile"}, &pb.FunctionConfig{}, tt.assigner, WithAllocationRetries(1))
+
+			err := d.RunOnce(context.Background())
+			assert.ErrorIs(t, err, signErr)
+			var allocErr *AllocationError
+			assert.True(t, errors.As(err, &allocErr))
+			// The tickets are returned to the pool instead of waiting for the pending timeout.
+			assert.Len(t, backend.assigned, tt.assigned)
+			assert.Equal(t, tt.released, backend.released)
+			assert.Equal(t, len(tt.released), d.Stats().ReleasedTickets)
+		})
+	}
+}
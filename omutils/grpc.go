@@ -1,27 +1,163 @@
 package omutils
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"open-match.dev/open-match/pkg/pb"
 )
 
-func NewOMFrontendClient(addr string) (pb.FrontendServiceClient, error) {
-	opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-	cc, err := grpc.Dial(addr, opts)
+const (
+	// DefaultCallTimeout is the deadline of unary calls without their own deadline.
+	DefaultCallTimeout = 10 * time.Second
+	// DefaultKeepaliveTime is the same as the default minimum ping interval of gRPC servers;
+	// a shorter interval makes the server close the connection with "too_many_pings".
+	DefaultKeepaliveTime    = 5 * time.Minute
+	DefaultKeepaliveTimeout = 20 * time.Second
+)
+
+// defaultServiceConfig balances the connections over all addresses resolved by DNS (e.g. headless Services),
+// and retries calls that fail before reaching the server (e.g. a restarting pod).
+// Only the read methods are retried; a retried CreateTicket or CreateBackfill may create a duplicate
+// when the response was lost.
+const defaultServiceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"methodConfig": [{
+		"name": [
+			{"service": "openmatch.FrontendService", "method": "GetTicket"},
+			{"service": "openmatch.FrontendService", "method": "GetBackfill"},
+			{"service": "openmatch.FrontendService", "method": "WatchAssignments"},
+			{"service": "openmatch.QueryService", "method": "QueryTickets"},
+			{"service": "openmatch.QueryService", "method": "QueryTicketIds"},
+			{"service": "openmatch.QueryService", "method": "QueryBackfills"}
+		],
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "1s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+type clientOptions struct {
+	tls           *TLSConfig
+	callTimeout   time.Duration
+	keepalive     keepalive.ClientParameters
+	serviceConfig string
+	dialOptions   []grpc.DialOption
+}
+
+// ClientOption configures the Open Match clients.
+type ClientOption func(*clientOptions)
+
+// WithTLS enables TLS on the client. A nil or empty config means an insecure connection.
+func WithTLS(config *TLSConfig) ClientOption {
+	return func(o *clientOptions) {
+		o.tls = config
+	}
+}
+
+// WithCallTimeout sets the deadline of unary calls without their own deadline (0 disables it).
+// Streaming calls (e.g. WatchAssignments) are not affected.
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.callTimeout = d
+	}
+}
+
+// WithKeepalive sets the interval of keepalive pings on active streams and the timeout of their acks.
+func WithKeepalive(interval, timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.keepalive = keepalive.ClientParameters{Time: interval, Timeout: timeout}
+	}
+}
+
+// WithServiceConfig replaces the default service config (round_robin and retries of the read methods on UNAVAILABLE).
+func WithServiceConfig(config string) ClientOption {
+	return func(o *clientOptions) {
+		o.serviceConfig = config
+	}
+}
+
+// WithDialOptions appends raw gRPC dial options.
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(o *clientOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+func NewOMFrontendClient(addr string, opts ...ClientOption) (pb.FrontendServiceClient, error) {
+	cc, err := Dial(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial to open match frontend: %w", err)
 	}
 	return pb.NewFrontendServiceClient(cc), nil
 }
 
-func NewOMBackendClient(addr string) (pb.BackendServiceClient, error) {
-	opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-	cc, err := grpc.Dial(addr, opts)
+func NewOMBackendClient(addr string, opts ...ClientOption) (pb.BackendServiceClient, error) {
+	cc, err := Dial(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial to open match backend: %w", err)
 	}
 	return pb.NewBackendServiceClient(cc), nil
 }
+
+func NewOMQueryClient(addr string, opts ...ClientOption) (pb.QueryServiceClient, error) {
+	cc, err := Dial(addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial to open match query: %w", err)
+	}
+	return pb.NewQueryServiceClient(cc), nil
+}
+
+// Dial creates a client connection with the default resilience settings.
+// An address without a scheme is resolved by DNS, so that all pods behind a headless Service are used.
+func Dial(addr string, opts ...ClientOption) (*grpc.ClientConn, error) {
+	o := &clientOptions{
+		callTimeout:   DefaultCallTimeout,
+		keepalive:     keepalive.ClientParameters{Time: DefaultKeepaliveTime, Timeout: DefaultKeepaliveTimeout},
+		serviceConfig: defaultServiceConfig,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	creds := insecure.NewCredentials()
+	if o.tls.Enabled() {
+		c, err := o.tls.ClientCredentials()
+		if err != nil {
+			return nil, err
+		}
+		creds = c
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(o.keepalive),
+		grpc.WithDefaultServiceConfig(o.serviceConfig),
+	}
+	if o.callTimeout > 0 {
+		dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(callTimeoutInterceptor(o.callTimeout)))
+	}
+	dialOpts = append(dialOpts, o.dialOptions...)
+	if !strings.Contains(addr, "://") {
+		addr = "dns:///" + addr
+	}
+	return grpc.Dial(addr, dialOpts...)
+}
+
+func callTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,74 @@
+package omutils
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestCallTimeoutInterceptor(t *testing.T) {
+	interceptor := callTimeoutInterceptor(1 * time.Second)
+	deadlineOf := func(ctx context.Context) time.Duration {
+		var remaining time.Duration
+		_ = interceptor(ctx, "/test", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			if deadline, ok := ctx.Deadline(); ok {
+				remaining = time.Until(deadline)
+			}
+			return nil
+		})
+		return remaining
+	}
+
+	// the default deadline
+	remaining := deadlineOf(context.Background())
+	assert.True(t, remaining > 0 && remaining <= 1*time.Second, remaining)
+
+	// The deadline of the caller takes precedence.
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	assert.True(t, deadlineOf(ctx) > 1*time.Second)
+}
+
+func TestDefaultServiceConfigRetriesReadMethods(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server is unavailable for every method and counts the attempts.
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		mu.Lock()
+		attempts[method]++
+		mu.Unlock()
+		return status.Error(codes.Unavailable, "unavailable")
+	}))
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	cc, err := Dial(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	for _, method := range []string{"/openmatch.FrontendService/GetTicket", "/openmatch.FrontendService/CreateTicket", "/openmatch.BackendService/AssignTickets"} {
+		err := cc.Invoke(context.Background(), method, &emptypb.Empty{}, &emptypb.Empty{})
+		assert.Equal(t, codes.Unavailable, status.Code(err), method)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 4, attempts["/openmatch.FrontendService/GetTicket"])
+	// Non-idempotent methods are not retried.
+	assert.Equal(t, 1, attempts["/openmatch.FrontendService/CreateTicket"])
+	assert.Equal(t, 1, attempts["/openmatch.BackendService/AssignTickets"])
+}
@@ -0,0 +1,92 @@
+package omutils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultJoinTokenTTL = 10 * time.Minute
+
+var (
+	ErrInvalidJoinToken = errors.New("invalid join token")
+	ErrJoinTokenExpired = errors.New("join token expired")
+)
+
+// JoinClaims is the content of a join token: the player of the ticket may join the match on the game server.
+type JoinClaims struct {
+	Connection string `json:"conn"`
+	TicketID   string `json:"ticket"`
+	MatchID    string `json:"match"`
+	// ExpiresAt is the expiry in Unix seconds.
+	ExpiresAt int64 `json:"exp"`
+}
+
+// JoinTokenSigner signs and verifies join tokens with HMAC-SHA256.
+// The director and the game servers share the key.
+type JoinTokenSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewJoinTokenSigner(key []byte) *JoinTokenSigner {
+	return &JoinTokenSigner{key: key, ttl: defaultJoinTokenTTL, now: time.Now}
+}
+
+// NewRandomJoinTokenSigner returns a signer with a random key, for the game servers in the same process.
+func NewRandomJoinTokenSigner() (*JoinTokenSigner, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate join token key: %w", err)
+	}
+	return NewJoinTokenSigner(key), nil
+}
+
+// Sign returns a token of the claims that expires after the TTL of the signer.
+func (s *JoinTokenSigner) Sign(claims JoinClaims) (string, error) {
+	claims.ExpiresAt = s.now().Add(s.ttl).Unix()
+	payload, err := json.Marshal(&claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.mac(payload)), nil
+}
+
+// Verify returns the claims of the token if it is signed with the key and not expired.
+func (s *JoinTokenSigner) Verify(token string) (*JoinClaims, error) {
+	enc := base64.RawURLEncoding
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidJoinToken
+	}
+	payload, err := enc.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidJoinToken
+	}
+	mac, err := enc.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+		return nil, ErrInvalidJoinToken
+	}
+	var claims JoinClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidJoinToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrJoinTokenExpired
+	}
+	return &claims, nil
+}
+
+func (s *JoinTokenSigner) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write(payload)
+	return h.Sum(nil)
+}
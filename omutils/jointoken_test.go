@@ -0,0 +1,33 @@
+package omutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinToken(t *testing.T) {
+	signer := NewJoinTokenSigner([]byte("secret"))
+	token, err := signer.Sign(JoinClaims{Connection: "gs-1", TicketID: "t1", MatchID: "match-1"})
+	assert.NoError(t, err)
+
+	claims, err := signer.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "gs-1", claims.Connection)
+	assert.Equal(t, "t1", claims.TicketID)
+	assert.Equal(t, "match-1", claims.MatchID)
+
+	// Signed with another key
+	_, err = NewJoinTokenSigner([]byte("other")).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidJoinToken)
+	// Tampered
+	_, err = signer.Verify("x" + token)
+	assert.ErrorIs(t, err, ErrInvalidJoinToken)
+	_, err = signer.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidJoinToken)
+
+	signer.now = func() time.Time { return time.Now().Add(defaultJoinTokenTTL + time.Second) }
+	_, err = signer.Verify(token)
+	assert.ErrorIs(t, err, ErrJoinTokenExpired)
+}
@@ -0,0 +1,118 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	fileMutexRetry = 10 * time.Millisecond
+	// fileMutexStale is the age of the mutex file left by a crashed process.
+	fileMutexStale = 10 * time.Second
+)
+
+// FileLock is a Lock shared by the processes on the same host (or a shared volume) via a file.
+// The file holds the holder and the expiry of the lease in JSON.
+type FileLock struct {
+	path string
+	now  func() time.Time
+}
+
+type fileLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path, now: time.Now}
+}
+
+func (l *FileLock) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	acquired := false
+	err := l.update(ctx, func(lease *fileLease) bool {
+		now := l.now()
+		if lease.Holder != "" && lease.Holder != holder && now.Before(lease.ExpiresAt) {
+			return false
+		}
+		lease.Holder = holder
+		lease.ExpiresAt = now.Add(ttl)
+		acquired = true
+		return true
+	})
+	return acquired, err
+}
+
+func (l *FileLock) Release(ctx context.Context, holder string) error {
+	return l.update(ctx, func(lease *fileLease) bool {
+		if lease.Holder != holder {
+			return false
+		}
+		*lease = fileLease{}
+		return true
+	})
+}
+
+// update reads, modifies and writes the lease exclusively among the processes.
+func (l *FileLock) update(ctx context.Context, modify func(lease *fileLease) bool) error {
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var lease fileLease
+	b, err := os.ReadFile(l.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read lease: %w", err)
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &lease); err != nil {
+			return fmt.Errorf("failed to parse lease %s: %w", l.path, err)
+		}
+	}
+	if !modify(&lease) {
+		return nil
+	}
+	b, err = json.Marshal(&lease)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so that a crash never leaves a broken lease.
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write lease: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to write lease: %w", err)
+	}
+	return nil
+}
+
+// lock creates the mutex file exclusively; it is portable unlike flock.
+func (l *FileLock) lock(ctx context.Context) (func(), error) {
+	mutex := l.path + ".lock"
+	for {
+		f, err := os.OpenFile(mutex, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(mutex) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock lease: %w", err)
+		}
+		if info, err := os.Stat(mutex); err == nil && time.Since(info.ModTime()) > fileMutexStale {
+			_ = os.Remove(mutex)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fileMutexRetry):
+		}
+	}
+}
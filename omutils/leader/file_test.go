@@ -0,0 +1,47 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "director.lease")
+	now := time.Now()
+	// Two processes share the lease via the file.
+	lock1, lock2 := NewFileLock(path), NewFileLock(path)
+	lock1.now = func() time.Time { return now }
+	lock2.now = func() time.Time { return now }
+
+	acquired, err := lock1.TryAcquire(ctx, "replica-1", 10*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = lock2.TryAcquire(ctx, "replica-2", 10*time.Second)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// The lease can be taken over after it expires.
+	now = now.Add(11 * time.Second)
+	acquired, err = lock2.TryAcquire(ctx, "replica-2", 10*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = lock1.TryAcquire(ctx, "replica-1", 10*time.Second)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Releasing by a non-holder is ignored.
+	assert.NoError(t, lock1.Release(ctx, "replica-1"))
+	acquired, err = lock1.TryAcquire(ctx, "replica-1", 10*time.Second)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	assert.NoError(t, lock2.Release(ctx, "replica-2"))
+	acquired, err = lock1.TryAcquire(ctx, "replica-1", 10*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+}
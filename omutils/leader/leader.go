@@ -0,0 +1,145 @@
+// Package leader elects one of the replicas (e.g. of the director) as the leader with a lease-based Lock,
+// so that the replicas don't run the same work twice.
+//
+//	err := leader.Run(ctx, lock, &leader.Config{Identity: hostname}, func(ctx context.Context) {
+//		_ = director.Run(ctx, interval)
+//	})
+package leader
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+var logger = logging.Component("leader")
+
+// Lock is a lease owned by at most one holder at a time.
+type Lock interface {
+	// TryAcquire acquires or renews the lease for the holder for ttl, and reports whether the holder owns it.
+	// The lease of another holder can be taken over after it expires.
+	TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if the holder owns it.
+	Release(ctx context.Context, holder string) error
+}
+
+type Config struct {
+	// Identity is the unique name of the replica, e.g. the Pod name.
+	Identity string
+	// LeaseDuration is how long the lease is valid without renewal (default 15s).
+	// A new leader is elected within this time after the leader dies.
+	LeaseDuration time.Duration
+	// RetryPeriod is the interval to renew or acquire the lease (default 2s).
+	RetryPeriod time.Duration
+}
+
+// Run campaigns for the leadership until ctx is done,
+// and runs lead while this replica is the leader. The context of lead is cancelled when the leadership is lost.
+// It returns after lead returns by itself, releasing the lease.
+func Run(ctx context.Context, lock Lock, config *Config, lead func(ctx context.Context)) error {
+	if config.Identity == "" {
+		return errors.New("identity of the leader election is empty")
+	}
+	leaseDuration := config.LeaseDuration
+	if leaseDuration == 0 {
+		leaseDuration = defaultLeaseDuration
+	}
+	retryPeriod := config.RetryPeriod
+	if retryPeriod == 0 {
+		retryPeriod = defaultRetryPeriod
+	}
+	// Step down before the lease expires, so that two leaders don't overlap.
+	renewDeadline := leaseDuration * 2 / 3
+	log := logger.With("identity", config.Identity)
+
+	var (
+		current   *leadership
+		lastRenew time.Time
+		// finished receives when lead returns by itself.
+		finished = make(chan struct{}, 1)
+	)
+	stepDown := func() {
+		if current == nil {
+			return
+		}
+		current.stop()
+		current = nil
+		log.Info("stopped leading")
+	}
+	defer func() {
+		stepDown()
+		releaseCtx, cancel := context.WithTimeout(context.Background(), retryPeriod)
+		defer cancel()
+		if err := lock.Release(releaseCtx, config.Identity); err != nil {
+			log.Warn("failed to release the lease", "error", err)
+		}
+	}()
+
+	ticker := time.NewTicker(retryPeriod)
+	defer ticker.Stop()
+	for {
+		acquired, err := lock.TryAcquire(ctx, config.Identity, leaseDuration)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Warn("failed to acquire the lease", "error", err)
+			if current != nil && time.Since(lastRenew) > renewDeadline {
+				log.Warn("failed to renew the lease in time")
+				stepDown()
+			}
+		case acquired:
+			lastRenew = time.Now()
+			if current == nil {
+				log.Info("started leading")
+				current = startLeading(ctx, lead, finished)
+			}
+		default:
+			if current != nil {
+				log.Warn("lost the lease")
+				stepDown()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-finished:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// leadership is a running lead function.
+type leadership struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startLeading(ctx context.Context, lead func(ctx context.Context), finished chan<- struct{}) *leadership {
+	ctx, cancel := context.WithCancel(ctx)
+	l := &leadership{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		lead(ctx)
+		if ctx.Err() == nil {
+			finished <- struct{}{}
+		}
+	}()
+	return l
+}
+
+// stop cancels lead and waits for it to return.
+func (l *leadership) stop() {
+	l.cancel()
+	<-l.done
+}
@@ -0,0 +1,80 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConfig(identity string) *Config {
+	return &Config{Identity: identity, LeaseDuration: 300 * time.Millisecond, RetryPeriod: 20 * time.Millisecond}
+}
+
+func TestRun(t *testing.T) {
+	lock := NewMemoryLock()
+	var leaders atomic.Int32
+	var led [2]atomic.Bool
+	lead := func(i int) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			led[i].Store(true)
+			// At most one replica leads at a time.
+			assert.Equal(t, int32(1), leaders.Add(1))
+			<-ctx.Done()
+			leaders.Add(-1)
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	done1 := make(chan error)
+	go func() { done1 <- Run(ctx1, lock, newTestConfig("replica-1"), lead(0)) }()
+	assert.Eventually(t, func() bool { return lock.Holder() == "replica-1" }, time.Second, 10*time.Millisecond)
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	done2 := make(chan error)
+	go func() { done2 <- Run(ctx2, lock, newTestConfig("replica-2"), lead(1)) }()
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, led[0].Load())
+	assert.False(t, led[1].Load())
+
+	// replica-2 takes over when replica-1 stops and releases the lease.
+	cancel1()
+	assert.NoError(t, <-done1)
+	assert.Eventually(t, func() bool { return led[1].Load() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "replica-2", lock.Holder())
+
+	cancel2()
+	assert.NoError(t, <-done2)
+	assert.Equal(t, "", lock.Holder())
+}
+
+func TestRunLostLease(t *testing.T) {
+	lock := NewMemoryLock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stopped atomic.Bool
+	go func() {
+		_ = Run(ctx, lock, newTestConfig("replica-1"), func(ctx context.Context) {
+			<-ctx.Done()
+			stopped.Store(true)
+		})
+	}()
+	assert.Eventually(t, func() bool { return lock.Holder() == "replica-1" }, time.Second, 10*time.Millisecond)
+
+	// Another replica takes the lease, e.g. after a network partition.
+	lock.mu.Lock()
+	lock.holder = "replica-2"
+	lock.expires = time.Now().Add(time.Hour)
+	lock.mu.Unlock()
+	assert.Eventually(t, stopped.Load, time.Second, 10*time.Millisecond)
+}
+
+func TestRunLeadReturns(t *testing.T) {
+	lock := NewMemoryLock()
+	err := Run(context.Background(), lock, newTestConfig("replica-1"), func(ctx context.Context) {})
+	assert.NoError(t, err)
+	assert.Equal(t, "", lock.Holder())
+}
@@ -0,0 +1,237 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTime is the format of metav1.MicroTime.
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// KubernetesLease is a Lock with a Lease (coordination.k8s.io/v1) of the Kubernetes API.
+// It needs the permissions to get, create and update the Lease (see cmd/testdirector/testdirector.yaml).
+type KubernetesLease struct {
+	baseURL   string
+	namespace string
+	name      string
+	client    *http.Client
+	// token returns the bearer token; it is read on each request because projected tokens are rotated.
+	token func() (string, error)
+	now   func() time.Time
+}
+
+// NewInClusterLease returns a KubernetesLease with the service account of the Pod.
+// The namespace is that of the Pod.
+func NewInClusterLease(name string) (*KubernetesLease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA of the cluster: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to parse CA of the cluster")
+	}
+	namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read namespace: %w", err)
+	}
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	token := func() (string, error) {
+		b, err := os.ReadFile(serviceAccountDir + "/token")
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return newKubernetesLease("https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(namespace)), name, client, token), nil
+}
+
+func newKubernetesLease(baseURL, namespace, name string, client *http.Client, token func() (string, error)) *KubernetesLease {
+	return &KubernetesLease{baseURL: baseURL, namespace: namespace, name: name, client: client, token: token, now: time.Now}
+}
+
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// errConflict is returned when another replica has updated the Lease concurrently.
+var errConflict = errors.New("lease was updated concurrently")
+
+func (l *KubernetesLease) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	now := l.now()
+	seconds := int(math.Ceil(ttl.Seconds()))
+	current, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		err := l.write(ctx, http.MethodPost, &lease{
+			Metadata: leaseMetadata{Name: l.name, Namespace: l.namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       holder,
+				LeaseDurationSeconds: seconds,
+				AcquireTime:          now.Format(microTime),
+				RenewTime:            now.Format(microTime),
+			},
+		})
+		if errors.Is(err, errConflict) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	spec := &current.Spec
+	if spec.HolderIdentity != "" && spec.HolderIdentity != holder && !expired(spec, now) {
+		return false, nil
+	}
+	if spec.HolderIdentity != holder {
+		spec.HolderIdentity = holder
+		spec.AcquireTime = now.Format(microTime)
+		spec.LeaseTransitions++
+	}
+	spec.LeaseDurationSeconds = seconds
+	spec.RenewTime = now.Format(microTime)
+	err = l.write(ctx, http.MethodPut, current)
+	if errors.Is(err, errConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *KubernetesLease) Release(ctx context.Context, holder string) error {
+	current, err := l.get(ctx)
+	if err != nil || current == nil || current.Spec.HolderIdentity != holder {
+		return err
+	}
+	// Let the other replicas take over the Lease immediately.
+	current.Spec.HolderIdentity = ""
+	current.Spec.LeaseDurationSeconds = 1
+	err = l.write(ctx, http.MethodPut, current)
+	if errors.Is(err, errConflict) {
+		return nil
+	}
+	return err
+}
+
+func expired(spec *leaseSpec, now time.Time) bool {
+	renewTime, err := time.Parse(microTime, spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return !now.Before(renewTime.Add(time.Duration(spec.LeaseDurationSeconds) * time.Second))
+}
+
+func (l *KubernetesLease) url(withName bool) string {
+	u := fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.baseURL, l.namespace)
+	if withName {
+		u += "/" + l.name
+	}
+	return u
+}
+
+// get returns the Lease, or nil if it doesn't exist.
+func (l *KubernetesLease) get(ctx context.Context) (*lease, error) {
+	resp, err := l.do(ctx, http.MethodGet, l.url(true), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("get", resp)
+	}
+	var current lease
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return &current, nil
+}
+
+// write creates (POST) or updates (PUT) the Lease. The update fails with errConflict if the resourceVersion is outdated.
+func (l *KubernetesLease) write(ctx context.Context, method string, ls *lease) error {
+	ls.APIVersion = "coordination.k8s.io/v1"
+	ls.Kind = "Lease"
+	body, err := json.Marshal(ls)
+	if err != nil {
+		return err
+	}
+	resp, err := l.do(ctx, method, l.url(method == http.MethodPut), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return errConflict
+	default:
+		return responseError(strings.ToLower(method), resp)
+	}
+}
+
+func (l *KubernetesLease) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	token, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request lease: %w", err)
+	}
+	return resp, nil
+}
+
+func responseError(op string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("failed to %s lease: %s: %s", op, resp.Status, strings.TrimSpace(string(b)))
+}
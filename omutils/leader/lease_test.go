@@ -0,0 +1,111 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLeaseServer serves a Lease with the optimistic concurrency of the Kubernetes API.
+type fakeLeaseServer struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+}
+
+func (s *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Path != "/apis/coordination.k8s.io/v1/namespaces/open-match/leases/testdirector" || s.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.lease)
+	case http.MethodPost, http.MethodPut:
+		var req lease
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if (r.Method == http.MethodPost && s.lease != nil) ||
+			(r.Method == http.MethodPut && (s.lease == nil || req.Metadata.ResourceVersion != s.lease.Metadata.ResourceVersion)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.version++
+		req.Metadata.ResourceVersion = strconv.Itoa(s.version)
+		s.lease = &req
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(s.lease)
+	}
+}
+
+func newTestLease(url string, now *time.Time) *KubernetesLease {
+	l := newKubernetesLease(url, "open-match", "testdirector", http.DefaultClient, func() (string, error) { return "test-token", nil })
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestKubernetesLease(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeLeaseServer{}
+	s := httptest.NewServer(fake)
+	defer s.Close()
+	now := time.Now()
+	lease1, lease2 := newTestLease(s.URL, &now), newTestLease(s.URL, &now)
+
+	acquired, err := lease1.TryAcquire(ctx, "replica-1", 15*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = lease2.TryAcquire(ctx, "replica-2", 15*time.Second)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	// Renewal
+	acquired, err = lease1.TryAcquire(ctx, "replica-1", 15*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, 15, fake.lease.Spec.LeaseDurationSeconds)
+
+	// replica-1 dies and its lease expires.
+	now = now.Add(16 * time.Second)
+	acquired, err = lease2.TryAcquire(ctx, "replica-2", 15*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "replica-2", fake.lease.Spec.HolderIdentity)
+	assert.Equal(t, 1, fake.lease.Spec.LeaseTransitions)
+
+	assert.NoError(t, lease1.Release(ctx, "replica-1"))
+	assert.Equal(t, "replica-2", fake.lease.Spec.HolderIdentity)
+	assert.NoError(t, lease2.Release(ctx, "replica-2"))
+	assert.Equal(t, "", fake.lease.Spec.HolderIdentity)
+	acquired, err = lease1.TryAcquire(ctx, "replica-1", 15*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestKubernetesLeaseConflict(t *testing.T) {
+	fake := &fakeLeaseServer{}
+	s := httptest.NewServer(fake)
+	defer s.Close()
+	now := time.Now()
+	l := newTestLease(s.URL, &now)
+	// Another replica updates the Lease between get and put.
+	assert.NoError(t, l.write(context.Background(), http.MethodPost, &lease{Metadata: leaseMetadata{Name: "testdirector"}}))
+	stale, err := l.get(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, l.write(context.Background(), http.MethodPut, stale))
+	assert.ErrorIs(t, l.write(context.Background(), http.MethodPut, stale), errConflict)
+}
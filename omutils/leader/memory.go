@@ -0,0 +1,50 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLock is a Lock shared by the replicas in the same process, e.g. in tests.
+type MemoryLock struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+	now     func() time.Time
+}
+
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{now: time.Now}
+}
+
+func (l *MemoryLock) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if l.holder != "" && l.holder != holder && now.Before(l.expires) {
+		return false, nil
+	}
+	l.holder = holder
+	l.expires = now.Add(ttl)
+	return true, nil
+}
+
+func (l *MemoryLock) Release(ctx context.Context, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == holder {
+		l.holder = ""
+	}
+	return nil
+}
+
+// Holder returns the current holder of the lease, or empty if it is free or expired.
+func (l *MemoryLock) Holder() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == "" || !l.now().Before(l.expires) {
+		return ""
+	}
+	return l.holder
+}
@@ -0,0 +1,177 @@
+// Package logging provides the structured logger shared by all components,
+// so that the logs of loadtest, director, match functions and simulator can be joined by the same fields.
+//
+// The logger is configured by environment variables:
+//
+//	LOG_FORMAT=json|text                 (default: json)
+//	LOG_LEVEL=debug|info|warn|error      (default: info)
+//	LOG_LEVELS=director=debug,loadtest=warn  (per component; overrides LOG_LEVEL)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// The keys of the fields to correlate the logs.
+const (
+	KeyComponent  = "component"
+	KeyTicketID   = "ticket_id"
+	KeyTicketIDs  = "ticket_ids"
+	KeyMatchID    = "match_id"
+	KeyBackfillID = "backfill_id"
+	KeyProfile    = "profile"
+	KeyPool       = "pool"
+)
+
+func TicketID(id string) slog.Attr     { return slog.String(KeyTicketID, id) }
+func TicketIDs(ids []string) slog.Attr { return slog.Any(KeyTicketIDs, ids) }
+func MatchID(id string) slog.Attr      { return slog.String(KeyMatchID, id) }
+func BackfillID(id string) slog.Attr   { return slog.String(KeyBackfillID, id) }
+func Profile(name string) slog.Attr    { return slog.String(KeyProfile, name) }
+func Pool(name string) slog.Attr       { return slog.String(KeyPool, name) }
+
+type Config struct {
+	// Format is "json" or "text".
+	Format          string
+	Level           slog.Level
+	ComponentLevels map[string]slog.Level
+	Output          io.Writer
+}
+
+// ConfigFromEnv returns Config from LOG_FORMAT, LOG_LEVEL and LOG_LEVELS.
+func ConfigFromEnv() (*Config, error) {
+	c := &Config{Format: "json", Output: os.Stderr}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		if format != "json" && format != "text" {
+			return nil, fmt.Errorf("unknown LOG_FORMAT: %s", format)
+		}
+		c.Format = format
+	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := c.Level.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+	}
+	levels, err := ParseComponentLevels(os.Getenv("LOG_LEVELS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVELS: %w", err)
+	}
+	c.ComponentLevels = levels
+	return c, nil
+}
+
+// ParseComponentLevels parses comma-separated levels of components, e.g. "director=debug,loadtest=warn".
+func ParseComponentLevels(s string) (map[string]slog.Level, error) {
+	levels := map[string]slog.Level{}
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		component, level, ok := strings.Cut(kv, "=")
+		if !ok || component == "" {
+			return nil, fmt.Errorf("invalid component level: %s (must be component=level)", kv)
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid level of %s: %w", component, err)
+		}
+		levels[component] = l
+	}
+	return levels, nil
+}
+
+type state struct {
+	handler slog.Handler
+	config  *Config
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	Setup(&Config{Format: "text", Output: os.Stderr})
+}
+
+// Setup replaces the handler of all loggers. It also becomes the default of slog and the standard log package.
+func Setup(c *Config) {
+	opts := &slog.HandlerOptions{
+		// The level is filtered by componentHandler.
+		Level: slog.LevelDebug,
+	}
+	var h slog.Handler
+	if c.Format == "json" {
+		h = slog.NewJSONHandler(c.Output, opts)
+	} else {
+		h = slog.NewTextHandler(c.Output, opts)
+	}
+	current.Store(&state{handler: h, config: c})
+	slog.SetDefault(slog.New(&componentHandler{}))
+}
+
+// SetupFromEnv calls Setup with ConfigFromEnv, and exits on invalid configuration.
+func SetupFromEnv() {
+	c, err := ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to configure logging: %+v\n", err)
+		os.Exit(2)
+	}
+	Setup(c)
+}
+
+// Component returns the logger of the component.
+// It can be created before Setup; the configuration at the time of logging is used.
+func Component(name string) *slog.Logger {
+	return slog.New(&componentHandler{component: name})
+}
+
+// Fatal logs the message at the error level and exits.
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// componentHandler filters records by the level of the component and delegates them to the current handler.
+type componentHandler struct {
+	component string
+	// ops are applied to the current handler in order (WithAttrs and WithGroup).
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	c := current.Load().config
+	min, ok := c.ComponentLevels[h.component]
+	if !ok {
+		min = c.Level
+	}
+	return level >= min
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := current.Load().handler
+	if h.component != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String(KeyComponent, h.component)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *componentHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1)
+	ops = append(ops, h.ops...)
+	return &componentHandler{component: h.component, ops: append(ops, op)}
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels("director=debug, loadtest=warn")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]slog.Level{"director": slog.LevelDebug, "loadtest": slog.LevelWarn}, levels)
+
+	_, err = ParseComponentLevels("director")
+	assert.Error(t, err)
+	_, err = ParseComponentLevels("director=verbose")
+	assert.Error(t, err)
+}
+
+func TestComponent(t *testing.T) {
+	var buf bytes.Buffer
+	// The logger is created before Setup, like package-level loggers.
+	director := Component("director").With(Profile("test-profile"))
+	loadtest := Component("loadtest")
+	Setup(&Config{
+		Format:          "json",
+		Level:           slog.LevelInfo,
+		ComponentLevels: map[string]slog.Level{"director": slog.LevelDebug, "loadtest": slog.LevelWarn},
+		Output:          &buf,
+	})
+	defer Setup(&Config{Format: "text", Output: &bytes.Buffer{}})
+
+	director.Debug("fetched matches", MatchID("match-1"), TicketIDs([]string{"t1", "t2"}))
+	loadtest.Info("ticket created", TicketID("t3"))
+	loadtest.Warn("ticket abandoned", TicketID("t4"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	var first, second map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "director", first[KeyComponent])
+	assert.Equal(t, "test-profile", first[KeyProfile])
+	assert.Equal(t, "match-1", first[KeyMatchID])
+	assert.Equal(t, []any{"t1", "t2"}, first[KeyTicketIDs])
+	assert.Equal(t, "loadtest", second[KeyComponent])
+	assert.Equal(t, "t4", second[KeyTicketID])
+}
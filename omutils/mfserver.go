@@ -0,0 +1,134 @@
+package omutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// MatchFunctionHealthService is the service name in the gRPC health service that reports the readiness of a Match Function.
+// The overall health (the empty service name) reports the liveness of the process.
+const MatchFunctionHealthService = "openmatch.MatchFunction"
+
+const readinessCheckInterval = 1 * time.Second
+
+var mfLogger = logging.Component("matchfunction")
+
+type MatchFunctionServerConfig struct {
+	// Ready checks the dependencies of the Match Function (e.g. CheckQueryService).
+	// The server reports NOT_SERVING until it succeeds.
+	Ready func(ctx context.Context) error
+	// DrainTimeout is the time to wait for in-flight Run streams to finish on shutdown.
+	DrainTimeout time.Duration
+	// ServerOptions are passed to grpc.NewServer.
+	ServerOptions []grpc.ServerOption
+	// HealthListener optionally serves the health service in plaintext for the gRPC probes of Kubernetes,
+	// which don't support TLS.
+	HealthListener net.Listener
+}
+
+// ServeMatchFunction serves the Match Function with the gRPC health service until ctx is done,
+// then stops gracefully.
+func ServeMatchFunction(ctx context.Context, lis net.Listener, mf pb.MatchFunctionServer, config *MatchFunctionServerConfig) error {
+	s := grpc.NewServer(config.ServerOptions...)
+	hs := health.NewServer()
+	hs.SetServingStatus(MatchFunctionHealthService, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	pb.RegisterMatchFunctionServer(s, mf)
+	if config.HealthListener != nil {
+		hsrv := grpc.NewServer()
+		healthpb.RegisterHealthServer(hsrv, hs)
+		go func() {
+			if err := hsrv.Serve(config.HealthListener); err != nil {
+				mfLogger.Error("failed to serve health", "error", err)
+			}
+		}()
+		defer hsrv.Stop()
+		mfLogger.Info("listening health", "addr", config.HealthListener.Addr().String())
+	}
+
+	checkCtx, cancelCheck := context.WithCancel(ctx)
+	defer cancelCheck()
+	go waitForReady(checkCtx, config.Ready, hs)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Serve(lis) }()
+	mfLogger.Info("listening", "addr", lis.Addr().String())
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Stop receiving new Run requests from the backend, then wait for in-flight ones.
+	mfLogger.Info("shutting down", "drain_timeout", config.DrainTimeout)
+	hs.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		mfLogger.Info("all streams finished")
+	case <-time.After(config.DrainTimeout):
+		mfLogger.Warn("drain timeout exceeded; closing the remaining streams")
+		s.Stop()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+func waitForReady(ctx context.Context, ready func(ctx context.Context) error, hs *health.Server) {
+	ticker := time.NewTicker(readinessCheckInterval)
+	defer ticker.Stop()
+	for {
+		err := ready(ctx)
+		if err == nil {
+			mfLogger.Info("match function is ready")
+			hs.SetServingStatus(MatchFunctionHealthService, healthpb.HealthCheckResponse_SERVING)
+			return
+		}
+		mfLogger.Warn("match function is not ready", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckQueryService verifies the connection to the QueryService by querying an empty pool.
+func CheckQueryService(qsc pb.QueryServiceClient) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		stream, err := qsc.QueryTickets(ctx, &pb.QueryTicketsRequest{Pool: &pb.Pool{
+			Name:              "healthcheck",
+			TagPresentFilters: []*pb.TagPresentFilter{{Tag: "openmatch-local-dev.healthcheck"}},
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to query tickets: %w", err)
+		}
+		for {
+			if _, err := stream.Recv(); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return fmt.Errorf("failed to query tickets: %w", err)
+			}
+		}
+	}
+}
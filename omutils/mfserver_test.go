@@ -0,0 +1,151 @@
+package omutils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestServeMatchFunction(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ready atomic.Bool
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeMatchFunction(ctx, lis, &pb.UnimplementedMatchFunctionServer{}, &MatchFunctionServerConfig{
+			Ready: func(ctx context.Context) error {
+				if !ready.Load() {
+					return errors.New("query service is not available")
+				}
+				return nil
+			},
+			DrainTimeout: 1 * time.Second,
+		})
+	}()
+
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	hc := healthpb.NewHealthClient(cc)
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(MatchFunctionHealthService))
+
+	ready.Store(true)
+	assert.Eventually(t, func() bool {
+		return status(MatchFunctionHealthService) == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 100*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+// blockingMatchFunction sends a proposal after release is closed.
+type blockingMatchFunction struct {
+	pb.UnimplementedMatchFunctionServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (mf *blockingMatchFunction) Run(req *pb.RunRequest, stream pb.MatchFunction_RunServer) error {
+	close(mf.started)
+	<-mf.release
+	return stream.Send(&pb.RunResponse{Proposal: &pb.Match{MatchId: "match-1"}})
+}
+
+func TestServeMatchFunctionDrainsRun(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf := &blockingMatchFunction{started: make(chan struct{}), release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeMatchFunction(ctx, lis, mf, &MatchFunctionServerConfig{
+			Ready:          func(ctx context.Context) error { return nil },
+			DrainTimeout:   5 * time.Second,
+			HealthListener: healthLis,
+		})
+	}()
+
+	dial := func(addr string) *grpc.ClientConn {
+		cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cc.Close() })
+		return cc
+	}
+	hc := healthpb.NewHealthClient(dial(healthLis.Addr().String()))
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: MatchFunctionHealthService})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return resp.Status
+	}
+	assert.Eventually(t, func() bool { return status() == healthpb.HealthCheckResponse_SERVING }, 5*time.Second, 100*time.Millisecond)
+
+	stream, err := pb.NewMatchFunctionClient(dial(lis.Addr().String())).Run(context.Background(), &pb.RunRequest{Profile: &pb.MatchProfile{Name: "test-profile"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-mf.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not start")
+	}
+
+	// The server reports NOT_SERVING and waits for the in-flight Run.
+	cancel()
+	assert.Eventually(t, func() bool { return status() == healthpb.HealthCheckResponse_NOT_SERVING }, 5*time.Second, 50*time.Millisecond)
+	assert.Never(t, func() bool { return len(served) > 0 }, 200*time.Millisecond, 20*time.Millisecond)
+
+	close(mf.release)
+	resp, err := stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "match-1", resp.Proposal.MatchId)
+	}
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
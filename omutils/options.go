@@ -0,0 +1,64 @@
+package omutils
+
+import (
+	"time"
+
+	"open-match.dev/open-match/pkg/pb"
+)
+
+type directorOptions struct {
+	observers         []MatchObserver
+	frontend          pb.FrontendServiceClient
+	allocationRetries int
+	maxBackoff        time.Duration
+	failureThreshold  int
+	openDuration      time.Duration
+	clientOptions     []ClientOption
+}
+
+type DirectorOption func(*directorOptions)
+
+// WithObservers notifies the observers of the assigned matches.
+func WithObservers(observers ...MatchObserver) DirectorOption {
+	return func(o *directorOptions) {
+		o.observers = append(o.observers, observers...)
+	}
+}
+
+// WithFrontend lets the director delete the backfills of the matches without game servers.
+func WithFrontend(frontend pb.FrontendServiceClient) DirectorOption {
+	return func(o *directorOptions) {
+		o.frontend = frontend
+	}
+}
+
+// WithAllocationRetries sets how many times the director retries to allocate game servers for the matches
+// before releasing their tickets (default 1).
+func WithAllocationRetries(n int) DirectorOption {
+	return func(o *directorOptions) {
+		o.allocationRetries = n
+	}
+}
+
+// WithClientOptions configures the backend client dialed by NewTestDirector, e.g. WithTLS.
+func WithClientOptions(opts ...ClientOption) DirectorOption {
+	return func(o *directorOptions) {
+		o.clientOptions = append(o.clientOptions, opts...)
+	}
+}
+
+// WithBackoff sets the upper limit of the exponential backoff after failures (default 1m).
+func WithBackoff(max time.Duration) DirectorOption {
+	return func(o *directorOptions) {
+		o.maxBackoff = max
+	}
+}
+
+// WithCircuitBreaker pauses the profile for openDuration after threshold consecutive failures
+// (default 5 failures and 30s).
+func WithCircuitBreaker(threshold int, openDuration time.Duration) DirectorOption {
+	return func(o *directorOptions) {
+		o.failureThreshold = threshold
+		o.openDuration = openDuration
+	}
+}
@@ -0,0 +1,95 @@
+package omutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"open-match.dev/open-match/pkg/matchfunction"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// SearchFieldsForPool returns SearchFields that match the filters of the pool,
+// e.g. so that a backfill created for the pool can be found by querying the same pool.
+func SearchFieldsForPool(pool *pb.Pool) *pb.SearchFields {
+	sf := &pb.SearchFields{}
+	if pool == nil {
+		return sf
+	}
+	for _, f := range pool.TagPresentFilters {
+		sf.Tags = append(sf.Tags, f.Tag)
+	}
+	for _, f := range pool.StringEqualsFilters {
+		if sf.StringArgs == nil {
+			sf.StringArgs = map[string]string{}
+		}
+		sf.StringArgs[f.StringArg] = f.Value
+	}
+	for _, f := range pool.DoubleRangeFilters {
+		if sf.DoubleArgs == nil {
+			sf.DoubleArgs = map[string]float64{}
+		}
+		sf.DoubleArgs[f.DoubleArg] = valueInRange(f)
+	}
+	return sf
+}
+
+// valueInRange returns a value that passes the filter.
+// An open-ended range is written with an infinite bound, so the finite bound is used instead of the midpoint.
+func valueInRange(f *pb.DoubleRangeFilter) float64 {
+	minInf, maxInf := math.IsInf(f.Min, 0), math.IsInf(f.Max, 0)
+	switch {
+	case minInf && maxInf:
+		return 0
+	case minInf:
+		if f.Exclude == pb.DoubleRangeFilter_MAX || f.Exclude == pb.DoubleRangeFilter_BOTH {
+			return math.Nextafter(f.Max, math.Inf(-1))
+		}
+		return f.Max
+	case maxInf:
+		if f.Exclude == pb.DoubleRangeFilter_MIN || f.Exclude == pb.DoubleRangeFilter_BOTH {
+			return math.Nextafter(f.Min, math.Inf(1))
+		}
+		return f.Min
+	default:
+		// The midpoint is within the range regardless of the excluded bounds.
+		return (f.Min + f.Max) / 2
+	}
+}
+
+// ErrUnfilteredPool is returned by CleanupPool and BackfillReaper for a pool without filters, which matches every ticket and backfill.
+var ErrUnfilteredPool = errors.New("pool has no filters")
+
+// IsUnfiltered reports whether the pool has no filters and matches every ticket and backfill.
+func IsUnfiltered(pool *pb.Pool) bool {
+	return len(pool.TagPresentFilters) == 0 && len(pool.StringEqualsFilters) == 0 && len(pool.DoubleRangeFilters) == 0
+}
+
+// CleanupPool deletes all tickets and backfills in the pool, and returns the number of deleted ones.
+// Tickets pending in proposals are not returned by the QueryService, so they are not deleted.
+// A pool without filters is rejected with ErrUnfilteredPool so that other tests' tickets are not deleted by mistake.
+func CleanupPool(ctx context.Context, omFrontend pb.FrontendServiceClient, omQuery pb.QueryServiceClient, pool *pb.Pool) (int, int, error) {
+	if IsUnfiltered(pool) {
+		return 0, 0, fmt.Errorf("failed to cleanup pool '%s': %w", pool.Name, ErrUnfilteredPool)
+	}
+	tickets, err := matchfunction.QueryPool(ctx, omQuery, pool)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query tickets: %w", err)
+	}
+	for _, ticket := range tickets {
+		if _, err := omFrontend.DeleteTicket(ctx, &pb.DeleteTicketRequest{TicketId: ticket.Id}); err != nil {
+			return 0, 0, fmt.Errorf("failed to delete ticket '%s': %w", ticket.Id, err)
+		}
+	}
+	backfills, err := matchfunction.QueryBackfillPool(ctx, omQuery, pool)
+	if err != nil {
+		return len(tickets), 0, fmt.Errorf("failed to query backfills: %w", err)
+	}
+	for _, backfill := range backfills {
+		if _, err := omFrontend.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: backfill.Id}); err != nil {
+			return len(tickets), 0, fmt.Errorf("failed to delete backfill '%s': %w", backfill.Id, err)
+		}
+	}
+	return len(tickets), len(backfills), nil
+}
@@ -0,0 +1,55 @@
+package omutils
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestSearchFieldsForPool(t *testing.T) {
+	pool := &pb.Pool{
+		Name:                "test-pool",
+		TagPresentFilters:   []*pb.TagPresentFilter{{Tag: "test-tag"}},
+		StringEqualsFilters: []*pb.StringEqualsFilter{{StringArg: "region", Value: "asia"}},
+		DoubleRangeFilters:  []*pb.DoubleRangeFilter{{DoubleArg: "skill", Min: 1000, Max: 2000, Exclude: pb.DoubleRangeFilter_BOTH}},
+	}
+	sf := SearchFieldsForPool(pool)
+	assert.Equal(t, []string{"test-tag"}, sf.Tags)
+	assert.Equal(t, map[string]string{"region": "asia"}, sf.StringArgs)
+	assert.Equal(t, map[string]float64{"skill": 1500}, sf.DoubleArgs)
+
+	assert.Equal(t, &pb.SearchFields{}, SearchFieldsForPool(&pb.Pool{Name: "empty"}))
+}
+
+func TestSearchFieldsForOpenEndedPool(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *pb.DoubleRangeFilter
+		want   float64
+	}{
+		{"no max", &pb.DoubleRangeFilter{Min: 1000, Max: math.Inf(1)}, 1000},
+		{"no min", &pb.DoubleRangeFilter{Min: math.Inf(-1), Max: 2000}, 2000},
+		{"no bounds", &pb.DoubleRangeFilter{Min: math.Inf(-1), Max: math.Inf(1)}, 0},
+		{"no max, min excluded", &pb.DoubleRangeFilter{Min: 1000, Max: math.Inf(1), Exclude: pb.DoubleRangeFilter_MIN}, math.Nextafter(1000, math.Inf(1))},
+		{"no min, max excluded", &pb.DoubleRangeFilter{Min: math.Inf(-1), Max: 2000, Exclude: pb.DoubleRangeFilter_BOTH}, math.Nextafter(2000, math.Inf(-1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.DoubleArg = "skill"
+			sf := SearchFieldsForPool(&pb.Pool{Name: "test-pool", DoubleRangeFilters: []*pb.DoubleRangeFilter{tt.filter}})
+			assert.Equal(t, tt.want, sf.DoubleArgs["skill"])
+		})
+	}
+}
+
+func TestCleanupUnfilteredPool(t *testing.T) {
+	assert.True(t, IsUnfiltered(&pb.Pool{Name: "all"}))
+	assert.False(t, IsUnfiltered(&pb.Pool{Name: "tagged", TagPresentFilters: []*pb.TagPresentFilter{{Tag: "test"}}}))
+
+	// It fails before calling Open Match.
+	_, _, err := CleanupPool(context.Background(), nil, nil, &pb.Pool{Name: "all"})
+	assert.ErrorIs(t, err, ErrUnfilteredPool)
+}
@@ -0,0 +1,251 @@
+// Package quality evaluates how good a match is, so that match functions can score proposals
+// and load tests can compare matchmaking algorithms.
+//
+// The metrics are computed from the SearchFields of the tickets:
+//   - DoubleArgs "skill": a skill rating of the player
+//   - StringArgs "team": a team of the player (each ticket is its own team if absent)
+//   - StringArgs "region": a region of the player
+//   - DoubleArgs "latency.<region>": a latency in milliseconds from the player to the region
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	skillKey         = "skill"
+	teamKey          = "team"
+	regionKey        = "region"
+	latencyKeyPrefix = "latency."
+
+	// CrossRegionLatency is the assumed latency to another region when the ticket has no latency field for it.
+	CrossRegionLatency = 150 * time.Millisecond
+)
+
+// Quality is the quality of a single match.
+type Quality struct {
+	// SkillSpread is the difference between the highest and lowest skill in the match.
+	SkillSpread float64
+	// TeamRatingDelta is the difference between the highest and lowest average skill of the teams.
+	TeamRatingDelta float64
+	// Region is the most common region in the match.
+	Region string
+	// RegionLatency is the worst latency of the players to the region of the match.
+	RegionLatency time.Duration
+	// WaitTimeVariance is the variance of the wait times of the tickets in seconds squared.
+	WaitTimeVariance float64
+	// FillRatio is the ratio of the players in the game server to its capacity after the match.
+	FillRatio float64
+}
+
+// Evaluate computes the quality of the match at the given time.
+// capacity is the number of players a game server can hold.
+func Evaluate(match *pb.Match, capacity int, now time.Time) Quality {
+	q := Quality{
+		SkillSpread:      skillSpread(match.Tickets),
+		TeamRatingDelta:  teamRatingDelta(match.Tickets),
+		WaitTimeVariance: waitTimeVariance(match.Tickets, now),
+		FillRatio:        fillRatio(match, capacity),
+	}
+	q.Region, q.RegionLatency = regionLatency(match.Tickets)
+	return q
+}
+
+// Weights are the weights of each metric to compute a score.
+type Weights struct {
+	// SkillSpread is the penalty per skill point of SkillSpread.
+	SkillSpread float64
+	// TeamRatingDelta is the penalty per skill point of TeamRatingDelta.
+	TeamRatingDelta float64
+	// RegionLatency is the penalty per second of RegionLatency.
+	RegionLatency float64
+	// WaitTimeVariance is the penalty per second squared of WaitTimeVariance.
+	WaitTimeVariance float64
+	// FillRatio is the bonus of a full game server.
+	FillRatio float64
+}
+
+var DefaultWeights = Weights{
+	SkillSpread:      0.001,
+	TeamRatingDelta:  0.002,
+	RegionLatency:    2,
+	WaitTimeVariance: 0.001,
+	FillRatio:        1,
+}
+
+// Score combines the metrics into a single value; the higher, the better.
+func (q Quality) Score(w Weights) float64 {
+	return w.FillRatio*q.FillRatio -
+		w.SkillSpread*q.SkillSpread -
+		w.TeamRatingDelta*q.TeamRatingDelta -
+		w.RegionLatency*q.RegionLatency.Seconds() -
+		w.WaitTimeVariance*q.WaitTimeVariance
+}
+
+func (q Quality) String() string {
+	return fmt.Sprintf("skillSpread: %.1f, teamRatingDelta: %.1f, region: %s, regionLatency: %s, waitTimeVariance: %.2f, fillRatio: %.2f",
+		q.SkillSpread, q.TeamRatingDelta, q.Region, q.RegionLatency, q.WaitTimeVariance, q.FillRatio)
+}
+
+func skillSpread(tickets []*pb.Ticket) float64 {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, t := range tickets {
+		skill, ok := t.GetSearchFields().GetDoubleArgs()[skillKey]
+		if !ok {
+			continue
+		}
+		min = math.Min(min, skill)
+		max = math.Max(max, skill)
+	}
+	if max < min {
+		return 0
+	}
+	return max - min
+}
+
+func teamRatingDelta(tickets []*pb.Ticket) float64 {
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, t := range tickets {
+		skill, ok := t.GetSearchFields().GetDoubleArgs()[skillKey]
+		if !ok {
+			continue
+		}
+		team, ok := t.GetSearchFields().GetStringArgs()[teamKey]
+		if !ok {
+			team = t.Id
+		}
+		sums[team] += skill
+		counts[team]++
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	for team, sum := range sums {
+		avg := sum / float64(counts[team])
+		min = math.Min(min, avg)
+		max = math.Max(max, avg)
+	}
+	if max < min {
+		return 0
+	}
+	return max - min
+}
+
+func regionLatency(tickets []*pb.Ticket) (string, time.Duration) {
+	counts := map[string]int{}
+	var region string
+	for _, t := range tickets {
+		r, ok := t.GetSearchFields().GetStringArgs()[regionKey]
+		if !ok {
+			continue
+		}
+		counts[r]++
+		if counts[r] > counts[region] || (counts[r] == counts[region] && r < region) {
+			region = r
+		}
+	}
+	if region == "" {
+		return "", 0
+	}
+
+	var worst time.Duration
+	for _, t := range tickets {
+		var latency time.Duration
+		if ms, ok := t.GetSearchFields().GetDoubleArgs()[latencyKeyPrefix+region]; ok {
+			latency = time.Duration(ms * float64(time.Millisecond))
+		} else if t.GetSearchFields().GetStringArgs()[regionKey] != region {
+			latency = CrossRegionLatency
+		}
+		if latency > worst {
+			worst = latency
+		}
+	}
+	return region, worst
+}
+
+func waitTimeVariance(tickets []*pb.Ticket, now time.Time) float64 {
+	var waits []float64
+	for _, t := range tickets {
+		if t.CreateTime == nil {
+			continue
+		}
+		waits = append(waits, now.Sub(t.CreateTime.AsTime()).Seconds())
+	}
+	if len(waits) == 0 {
+		return 0
+	}
+	var sum, sqSum float64
+	for _, w := range waits {
+		sum += w
+		sqSum += w * w
+	}
+	n := float64(len(waits))
+	mean := sum / n
+	return math.Max(sqSum/n-mean*mean, 0)
+}
+
+func fillRatio(match *pb.Match, capacity int) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	players := len(match.Tickets)
+	if match.Backfill != nil {
+		// The backfill tells how many slots are left after this match.
+		if openSlots, err := omutils.GetOpenSlots(match.Backfill); err == nil {
+			players = capacity - int(openSlots)
+		}
+	}
+	return math.Min(float64(players)/float64(capacity), 1)
+}
+
+// Summary aggregates the quality of many matches to compare matchmaking algorithms.
+type Summary struct {
+	mu               sync.Mutex
+	matches          int
+	skillSpread      float64
+	teamRatingDelta  float64
+	regionLatency    time.Duration
+	waitTimeVariance float64
+	fillRatio        float64
+	score            float64
+}
+
+func (s *Summary) Add(q Quality) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matches++
+	s.skillSpread += q.SkillSpread
+	s.teamRatingDelta += q.TeamRatingDelta
+	s.regionLatency += q.RegionLatency
+	s.waitTimeVariance += q.WaitTimeVariance
+	s.fillRatio += q.FillRatio
+	s.score += q.Score(DefaultWeights)
+}
+
+func (s *Summary) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.matches == 0 {
+		return "matches: 0"
+	}
+	n := float64(s.matches)
+	return fmt.Sprintf("matches: %d, avg skillSpread: %.1f, teamRatingDelta: %.1f, regionLatency: %s, waitTimeVariance: %.2f, fillRatio: %.2f, score: %.3f",
+		s.matches, s.skillSpread/n, s.teamRatingDelta/n, s.regionLatency/time.Duration(s.matches), s.waitTimeVariance/n, s.fillRatio/n, s.score/n)
+}
+
+// Percentile returns the p-th percentile (nearest rank) of sorted durations, e.g. of the wait times.
+func Percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
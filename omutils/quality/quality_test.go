@@ -0,0 +1,100 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func newTicket(id string, skill float64, region string, createTime time.Time) *pb.Ticket {
+	return &pb.Ticket{
+		Id: id,
+		SearchFields: &pb.SearchFields{
+			DoubleArgs: map[string]float64{skillKey: skill},
+			StringArgs: map[string]string{regionKey: region},
+		},
+		CreateTime: timestamppb.New(createTime),
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+
+	t.Run("full match", func(t *testing.T) {
+		match := &pb.Match{Tickets: []*pb.Ticket{
+			newTicket("ticket-1", 1400, "asia", now.Add(-1*time.Second)),
+			newTicket("ticket-2", 1500, "asia", now.Add(-3*time.Second)),
+			newTicket("ticket-3", 1700, "us", now.Add(-5*time.Second)),
+		}}
+		q := Evaluate(match, 3, now)
+		assert.Equal(t, 300.0, q.SkillSpread)
+		assert.Equal(t, 300.0, q.TeamRatingDelta)
+		assert.Equal(t, "asia", q.Region)
+		assert.Equal(t, CrossRegionLatency, q.RegionLatency)
+		assert.InDelta(t, 8.0/3, q.WaitTimeVariance, 1e-9)
+		assert.Equal(t, 1.0, q.FillRatio)
+	})
+
+	t.Run("teams and latency fields", func(t *testing.T) {
+		tickets := []*pb.Ticket{
+			newTicket("ticket-1", 1400, "eu", now),
+			newTicket("ticket-2", 1600, "eu", now),
+			newTicket("ticket-3", 1500, "eu", now),
+			newTicket("ticket-4", 1700, "eu", now),
+		}
+		for i, team := range []string{"red", "red", "blue", "blue"} {
+			tickets[i].SearchFields.StringArgs[teamKey] = team
+		}
+		tickets[0].SearchFields.DoubleArgs[latencyKeyPrefix+"eu"] = 42
+		q := Evaluate(&pb.Match{Tickets: tickets}, 4, now)
+		assert.Equal(t, 300.0, q.SkillSpread)
+		assert.Equal(t, 100.0, q.TeamRatingDelta)
+		assert.Equal(t, 42*time.Millisecond, q.RegionLatency)
+		assert.Equal(t, 0.0, q.WaitTimeVariance)
+	})
+
+	t.Run("backfill match", func(t *testing.T) {
+		backfill := &pb.Backfill{}
+		assert.NoError(t, omutils.SetOpenSlots(backfill, 1))
+		match := &pb.Match{Tickets: []*pb.Ticket{{Id: "ticket-1"}}, Backfill: backfill}
+		q := Evaluate(match, 3, now)
+		assert.InDelta(t, 2.0/3, q.FillRatio, 1e-9)
+		assert.Equal(t, 0.0, q.SkillSpread)
+		assert.Equal(t, "", q.Region)
+	})
+}
+
+func TestScore(t *testing.T) {
+	good := Quality{SkillSpread: 50, FillRatio: 1}
+	bad := Quality{SkillSpread: 800, RegionLatency: 150 * time.Millisecond, FillRatio: 1.0 / 3}
+	assert.Greater(t, good.Score(DefaultWeights), bad.Score(DefaultWeights))
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"single p0", []time.Duration{7}, 0, 7},
+		{"single p50", []time.Duration{7}, 50, 7},
+		{"single p100", []time.Duration{7}, 100, 7},
+		{"p0", sorted, 0, 1},
+		{"p50", sorted, 50, 5},
+		{"p90", sorted, 90, 9},
+		{"p99", sorted, 99, 10},
+		{"p100", sorted, 100, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Percentile(tt.sorted, tt.p))
+		})
+	}
+}
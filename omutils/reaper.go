@@ -0,0 +1,152 @@
+package omutils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"open-match.dev/open-match/pkg/matchfunction"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	defaultReaperGracePeriod = 10 * time.Second
+	defaultReaperAckDeadline = 5 * time.Second
+)
+
+var reaperLogger = logging.Component("reaper")
+
+// BackfillOwner reports the backfills of the live game servers with the time they were last acknowledged
+// (zero if not yet).
+type BackfillOwner interface {
+	OwnedBackfills() map[string]time.Time
+}
+
+// BackfillOwnerFunc is a BackfillOwner as a function.
+type BackfillOwnerFunc func() map[string]time.Time
+
+func (f BackfillOwnerFunc) OwnedBackfills() map[string]time.Time {
+	return f()
+}
+
+type BackfillReaperConfig struct {
+	// Pools are queried to find the backfills.
+	Pools []*pb.Pool
+	// GracePeriod keeps a backfill without owner younger than this,
+	// because the director may be starting the game server of a backfill created on FetchMatches (default 10s).
+	GracePeriod time.Duration
+	// AckDeadline is how long an owned backfill can be left unacknowledged before it is reported (default 5s).
+	AckDeadline time.Duration
+}
+
+// ReapResult is the result of BackfillReaper.ReapOnce.
+type ReapResult struct {
+	// Deleted is the IDs of the deleted backfills without live game servers.
+	Deleted []string
+	// Unacknowledged is the IDs of the backfills of live game servers not acknowledged within the deadline.
+	Unacknowledged []string
+}
+
+// BackfillReaper deletes the backfills whose game servers are gone,
+// so that Open Match doesn't route new tickets to dead game servers until the backfills expire.
+type BackfillReaper struct {
+	frontend pb.FrontendServiceClient
+	query    pb.QueryServiceClient
+	owner    BackfillOwner
+	config   BackfillReaperConfig
+	now      func() time.Time
+}
+
+// NewBackfillReaper returns a reaper of the backfills in the pools.
+// A pool without filters is rejected with ErrUnfilteredPool so that the backfills of other directors' game servers are not deleted.
+func NewBackfillReaper(frontend pb.FrontendServiceClient, query pb.QueryServiceClient, owner BackfillOwner, config *BackfillReaperConfig) (*BackfillReaper, error) {
+	if err := checkReaperPools(config.Pools); err != nil {
+		return nil, err
+	}
+	c := *config
+	if c.GracePeriod == 0 {
+		c.GracePeriod = defaultReaperGracePeriod
+	}
+	if c.AckDeadline == 0 {
+		c.AckDeadline = defaultReaperAckDeadline
+	}
+	return &BackfillReaper{frontend: frontend, query: query, owner: owner, config: c, now: time.Now}, nil
+}
+
+func checkReaperPools(pools []*pb.Pool) error {
+	for _, pool := range pools {
+		if IsUnfiltered(pool) {
+			return fmt.Errorf("failed to reap backfills in pool '%s': %w", pool.Name, ErrUnfilteredPool)
+		}
+	}
+	return nil
+}
+
+// Run runs ReapOnce every interval until ctx is done.
+func (r *BackfillReaper) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.ReapOnce(ctx); err != nil && ctx.Err() == nil {
+				reaperLogger.Error("failed to reap backfills", "error", err)
+			}
+		}
+	}
+}
+
+// ReapOnce deletes the orphaned backfills in the pools and reports the unacknowledged ones.
+func (r *BackfillReaper) ReapOnce(ctx context.Context) (*ReapResult, error) {
+	if err := checkReaperPools(r.config.Pools); err != nil {
+		return nil, err
+	}
+	backfills := map[string]*pb.Backfill{}
+	var ids []string
+	for _, pool := range r.config.Pools {
+		bs, err := matchfunction.QueryBackfillPool(ctx, r.query, pool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query backfills: %w", err)
+		}
+		for _, b := range bs {
+			if _, ok := backfills[b.Id]; !ok {
+				backfills[b.Id] = b
+				ids = append(ids, b.Id)
+			}
+		}
+	}
+
+	// The owners are listed after the query, so that a backfill created in between is not an orphan.
+	owned := r.owner.OwnedBackfills()
+	now := r.now()
+	result := &ReapResult{}
+	for _, id := range ids {
+		backfill := backfills[id]
+		createdAt := backfill.GetCreateTime().AsTime()
+		lastAck, ok := owned[id]
+		if !ok {
+			if now.Sub(createdAt) < r.config.GracePeriod {
+				continue
+			}
+			if _, err := r.frontend.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: id}); err != nil && status.Code(err) != codes.NotFound {
+				return result, fmt.Errorf("failed to delete backfill '%s': %w", id, err)
+			}
+			reaperLogger.Info("deleted orphaned backfill", logging.BackfillID(id), "age", now.Sub(createdAt))
+			result.Deleted = append(result.Deleted, id)
+			continue
+		}
+		if lastAck.IsZero() {
+			lastAck = createdAt
+		}
+		if now.Sub(lastAck) > r.config.AckDeadline {
+			reaperLogger.Warn("backfill is not acknowledged", logging.BackfillID(id), "since", now.Sub(lastAck))
+			result.Unacknowledged = append(result.Unacknowledged, id)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package omutils
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// fakeBackfillQuery returns the backfills for any pool.
+type fakeBackfillQuery struct {
+	pb.QueryServiceClient
+	backfills []*pb.Backfill
+}
+
+func (f *fakeBackfillQuery) QueryBackfills(ctx context.Context, in *pb.QueryBackfillsRequest, opts ...grpc.CallOption) (pb.QueryService_QueryBackfillsClient, error) {
+	return &fakeQueryBackfillsStream{backfills: f.backfills}, nil
+}
+
+type fakeQueryBackfillsStream struct {
+	grpc.ClientStream
+	backfills []*pb.Backfill
+	sent      bool
+}
+
+func (s *fakeQueryBackfillsStream) Recv() (*pb.QueryBackfillsResponse, error) {
+	if s.sent {
+		return nil, io.EOF
+	}
+	s.sent = true
+	return &pb.QueryBackfillsResponse{Backfills: s.backfills}, nil
+}
+
+func TestBackfillReaper(t *testing.T) {
+	now := time.Now()
+	created := func(ago time.Duration) *timestamppb.Timestamp { return timestamppb.New(now.Add(-ago)) }
+	query := &fakeBackfillQuery{backfills: []*pb.Backfill{
+		{Id: "live", CreateTime: created(time.Minute)},
+		{Id: "stuck", CreateTime: created(time.Minute)},
+		{Id: "orphan", CreateTime: created(time.Minute)},
+		// Its game server may be starting.
+		{Id: "new", CreateTime: created(time.Second)},
+	}}
+	frontend := &fakeBackfillFrontend{}
+	owner := BackfillOwnerFunc(func() map[string]time.Time {
+		return map[string]time.Time{
+			"live":  now.Add(-100 * time.Millisecond),
+			"stuck": now.Add(-30 * time.Second),
+		}
+	})
+	r, err := NewBackfillReaper(frontend, query, owner, &BackfillReaperConfig{Pools: []*pb.Pool{taggedPool("pool-1"), taggedPool("pool-2")}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	r.now = func() time.Time { return now }
+
+	result, err := r.ReapOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, result.Deleted)
+	assert.Equal(t, []string{"stuck"}, result.Unacknowledged)
+	// The backfill in both pools is deleted once.
+	assert.Equal(t, []string{"orphan"}, frontend.deleted)
+}
+
+func TestBackfillReaperUnfilteredPool(t *testing.T) {
+	_, err := NewBackfillReaper(&fakeBackfillFrontend{}, &fakeBackfillQuery{}, BackfillOwnerFunc(nil), &BackfillReaperConfig{Pools: []*pb.Pool{taggedPool("pool-1"), {Name: "all"}}})
+	assert.ErrorIs(t, err, ErrUnfilteredPool)
+
+	// The pools are checked again on each run, as the caller may change the filters.
+	frontend := &fakeBackfillFrontend{}
+	r := &BackfillReaper{frontend: frontend, query: &fakeBackfillQuery{backfills: []*pb.Backfill{{Id: "orphan"}}}, config: BackfillReaperConfig{Pools: []*pb.Pool{{Name: "all"}}}, now: time.Now}
+	_, err = r.ReapOnce(context.Background())
+	assert.ErrorIs(t, err, ErrUnfilteredPool)
+	assert.Empty(t, frontend.deleted)
+}
+
+func taggedPool(name string) *pb.Pool {
+	return &pb.Pool{Name: name, TagPresentFilters: []*pb.TagPresentFilter{{Tag: name}}}
+}
@@ -0,0 +1,207 @@
+// Package record records ticket traffic to a JSON Lines file so that a matchmaking run can be replayed.
+//
+// Each line is an Event. Tickets, matches and assignments are encoded in protobuf JSON.
+//
+//	{"offset":"1.2s","type":"ticket_created","ticket":{"id":"...","searchFields":{...}}}
+//	{"offset":"2s","type":"proposal","match":{"matchId":"...","tickets":[...]}}
+//	{"offset":"2s","type":"assignment","ticketIds":["..."],"assignment":{"connection":"..."}}
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var logger = logging.Component("record")
+
+type EventType string
+
+const (
+	EventTicketCreated EventType = "ticket_created"
+	EventProposal      EventType = "proposal"
+	EventAssignment    EventType = "assignment"
+)
+
+type Event struct {
+	// Offset is the elapsed time since the recording started, e.g. "1.5s".
+	Offset     string          `json:"offset"`
+	Type       EventType       `json:"type"`
+	Ticket     json.RawMessage `json:"ticket,omitempty"`
+	Match      json.RawMessage `json:"match,omitempty"`
+	TicketIDs  []string        `json:"ticketIds,omitempty"`
+	Assignment json.RawMessage `json:"assignment,omitempty"`
+}
+
+// Elapsed returns Offset as time.Duration.
+func (e *Event) Elapsed() (time.Duration, error) {
+	return time.ParseDuration(e.Offset)
+}
+
+func (e *Event) GetTicket() (*pb.Ticket, error) {
+	ticket := &pb.Ticket{}
+	if err := protojson.Unmarshal(e.Ticket, ticket); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func (e *Event) GetMatch() (*pb.Match, error) {
+	match := &pb.Match{}
+	if err := protojson.Unmarshal(e.Match, match); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+func (e *Event) GetAssignment() (*pb.Assignment, error) {
+	as := &pb.Assignment{}
+	if err := protojson.Unmarshal(e.Assignment, as); err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+// Recorder writes events to a file. A nil *Recorder records nothing,
+// so that callers don't have to check whether recording is enabled.
+// Each event is flushed as a whole line, so the recording survives a crash of the process.
+type Recorder struct {
+	mu    sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	start time.Time
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record file: %w", err)
+	}
+	return &Recorder{
+		f:     f,
+		w:     bufio.NewWriter(f),
+		start: time.Now(),
+	}, nil
+}
+
+func (r *Recorder) TicketCreated(ticket *pb.Ticket) error {
+	if r == nil {
+		return nil
+	}
+	b, err := marshal(ticket)
+	if err != nil {
+		return err
+	}
+	return r.write(&Event{Type: EventTicketCreated, Ticket: b})
+}
+
+func (r *Recorder) Proposal(match *pb.Match) error {
+	if r == nil {
+		return nil
+	}
+	b, err := marshal(match)
+	if err != nil {
+		return err
+	}
+	return r.write(&Event{Type: EventProposal, Match: b})
+}
+
+func (r *Recorder) Assignment(ticketIDs []string, assignment *pb.Assignment) error {
+	if r == nil {
+		return nil
+	}
+	b, err := marshal(assignment)
+	if err != nil {
+		return err
+	}
+	return r.write(&Event{Type: EventAssignment, TicketIDs: ticketIDs, Assignment: b})
+}
+
+// ObserveMatch records the proposal and its assignment; it can be used as omutils.MatchObserver.
+func (r *Recorder) ObserveMatch(match *pb.Match, assignment *pb.Assignment) {
+	if err := r.Proposal(match); err != nil {
+		logger.Error("failed to record proposal", logging.MatchID(match.MatchId), "error", err)
+	}
+	var ticketIDs []string
+	for _, ticket := range match.Tickets {
+		ticketIDs = append(ticketIDs, ticket.Id)
+	}
+	if err := r.Assignment(ticketIDs, assignment); err != nil {
+		logger.Error("failed to record assignment", logging.MatchID(match.MatchId), "error", err)
+	}
+}
+
+func (r *Recorder) write(e *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Offset = time.Since(r.start).String()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := r.w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	return r.f.Close()
+}
+
+func marshal(m proto.Message) (json.RawMessage, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", m, err)
+	}
+	return b, nil
+}
+
+// ReadEvents reads all events from a record file.
+func ReadEvents(path string) ([]*Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %w", err)
+	}
+	defer f.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", line, err)
+		}
+		if _, err := e.Elapsed(); err != nil {
+			return nil, fmt.Errorf("invalid offset at line %d: %w", line, err)
+		}
+		events = append(events, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read record file: %w", err)
+	}
+	return events, nil
+}
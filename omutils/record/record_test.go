@@ -0,0 +1,68 @@
+package record
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	r, err := NewRecorder(path)
+	assert.NoError(t, err)
+
+	ticket := &pb.Ticket{
+		Id: "ticket-1",
+		SearchFields: &pb.SearchFields{
+			DoubleArgs: map[string]float64{"skill": 1500},
+			StringArgs: map[string]string{"region": "asia"},
+		},
+	}
+	assert.NoError(t, r.TicketCreated(ticket))
+	r.ObserveMatch(&pb.Match{MatchId: "match-1", Tickets: []*pb.Ticket{ticket}}, &pb.Assignment{Connection: "gs-1"})
+	assert.NoError(t, r.Close())
+
+	events, err := ReadEvents(path)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	assert.Equal(t, EventTicketCreated, events[0].Type)
+	recorded, err := events[0].GetTicket()
+	assert.NoError(t, err)
+	assert.Equal(t, "ticket-1", recorded.Id)
+	assert.Equal(t, 1500.0, recorded.SearchFields.DoubleArgs["skill"])
+	assert.Equal(t, "asia", recorded.SearchFields.StringArgs["region"])
+
+	assert.Equal(t, EventProposal, events[1].Type)
+	match, err := events[1].GetMatch()
+	assert.NoError(t, err)
+	assert.Equal(t, "match-1", match.MatchId)
+
+	assert.Equal(t, EventAssignment, events[2].Type)
+	assert.Equal(t, []string{"ticket-1"}, events[2].TicketIDs)
+	as, err := events[2].GetAssignment()
+	assert.NoError(t, err)
+	assert.Equal(t, "gs-1", as.Connection)
+}
+
+func TestRecordWithoutClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	r, err := NewRecorder(path)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	// The events are readable even if the process exits without closing the recorder.
+	assert.NoError(t, r.TicketCreated(&pb.Ticket{Id: "ticket-1"}))
+	events, err := ReadEvents(path)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	assert.NoError(t, r.TicketCreated(&pb.Ticket{}))
+	r.ObserveMatch(&pb.Match{}, &pb.Assignment{})
+	assert.NoError(t, r.Close())
+}
@@ -0,0 +1,211 @@
+package omutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig is the files to enable TLS on the gRPC clients and servers.
+// The files are reloaded when they are modified, so that the certificates can be rotated without restarts.
+type TLSConfig struct {
+	// CAFile is the root CA to verify the peer.
+	// A server with CAFile requires a client certificate signed by it (mTLS).
+	CAFile string
+	// CertFile and KeyFile are the certificate presented to the peer.
+	// They are required for servers, and optional for clients (for mTLS).
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name to verify the server certificate (clients only).
+	ServerName string
+}
+
+// TLSConfigFromEnv returns TLSConfig from OM_TLS_CA_FILE, OM_TLS_CERT_FILE, OM_TLS_KEY_FILE and OM_TLS_SERVER_NAME,
+// or nil if none of the files are set.
+func TLSConfigFromEnv() *TLSConfig {
+	c := &TLSConfig{
+		CAFile:     os.Getenv("OM_TLS_CA_FILE"),
+		CertFile:   os.Getenv("OM_TLS_CERT_FILE"),
+		KeyFile:    os.Getenv("OM_TLS_KEY_FILE"),
+		ServerName: os.Getenv("OM_TLS_SERVER_NAME"),
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	return c
+}
+
+// RegisterFlags registers the flags of TLS files with the defaults from TLSConfigFromEnv.
+func (c *TLSConfig) RegisterFlags(fs *flag.FlagSet) {
+	env := TLSConfigFromEnv()
+	if env == nil {
+		env = &TLSConfig{}
+	}
+	fs.StringVar(&c.CAFile, "tls-ca", env.CAFile, "A path to CA certificate to verify Open Match (enables TLS)")
+	fs.StringVar(&c.CertFile, "tls-cert", env.CertFile, "A path to client certificate for mTLS")
+	fs.StringVar(&c.KeyFile, "tls-key", env.KeyFile, "A path to client key for mTLS")
+	fs.StringVar(&c.ServerName, "tls-server-name", env.ServerName, "A server name to verify the certificate of Open Match")
+}
+
+// Enabled reports whether any of the files are set.
+func (c *TLSConfig) Enabled() bool {
+	return c != nil && (c.CAFile != "" || c.CertFile != "" || c.KeyFile != "")
+}
+
+// ClientCredentials returns the credentials for gRPC clients.
+func (c *TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	r, err := newCertReloader(c)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _, err := r.load()
+			if err != nil {
+				return nil, err
+			}
+			if cert == nil {
+				// No client certificate is configured.
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+		// The server certificate is verified by VerifyConnection with the latest CA.
+		InsecureSkipVerify: c.CAFile != "",
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if c.CAFile == "" {
+				return nil
+			}
+			_, roots, err := r.load()
+			if err != nil {
+				return err
+			}
+			serverName := c.ServerName
+			if serverName == "" {
+				serverName = cs.ServerName
+			}
+			return verifyPeer(cs, roots, serverName, x509.ExtKeyUsageServerAuth)
+		},
+		ServerName: c.ServerName,
+	}), nil
+}
+
+// ServerCredentials returns the credentials for gRPC servers.
+func (c *TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("cert file and key file are required for TLS servers")
+	}
+	r, err := newCertReloader(c)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs, err := r.load()
+			if err != nil {
+				return nil, err
+			}
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if clientCAs != nil {
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				config.ClientCAs = clientCAs
+			}
+			return config, nil
+		},
+	}), nil
+}
+
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       dnsName,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
+
+// certReloader holds the certificate and the CA, and reloads them when the files are modified.
+type certReloader struct {
+	config *TLSConfig
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	roots   *x509.CertPool
+	modTime time.Time
+}
+
+func newCertReloader(c *TLSConfig) (*certReloader, error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("both cert file and key file are required")
+	}
+	r := &certReloader{config: c}
+	if _, _, err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reloads the files if the latest modification time of them is changed,
+// and returns the certificate (nil without CertFile) and the CA pool (nil without CAFile).
+func (r *certReloader) load() (*tls.Certificate, *x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var modTime time.Time
+	for _, file := range []string{r.config.CAFile, r.config.CertFile, r.config.KeyFile} {
+		if file == "" {
+			continue
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to stat TLS file: %w", err)
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if !r.modTime.IsZero() && modTime.Equal(r.modTime) {
+		return r.cert, r.roots, nil
+	}
+
+	if r.config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load key pair: %w", err)
+		}
+		r.cert = &cert
+	}
+	if r.config.CAFile != "" {
+		pem, err := os.ReadFile(r.config.CAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in CA file: %s", r.config.CAFile)
+		}
+		r.roots = roots
+	}
+	r.modTime = modTime
+	return r.cert, r.roots, nil
+}
@@ -0,0 +1,160 @@
+package omutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	server := &TLSConfig{
+		CAFile:   filepath.Join(dir, "ca.crt"),
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	}
+	client := &TLSConfig{
+		CAFile:   server.CAFile,
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+	}
+	writeCerts(t, dir, "ca-1", time.Now())
+
+	addr := serveHealth(t, server)
+	creds, err := client.ClientCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, checkHealth(addr, creds))
+
+	// without the client certificate (mTLS)
+	noCert, err := (&TLSConfig{CAFile: client.CAFile}).ClientCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, checkHealth(addr, noCert))
+
+	// Rotate all certificates with a new CA; both the server and the client reload them.
+	writeCerts(t, dir, "ca-2", time.Now().Add(1*time.Second))
+	assert.NoError(t, checkHealth(addr, creds))
+
+	// A client that still has the old CA cannot verify the rotated server certificate.
+	oldDir := t.TempDir()
+	writeCerts(t, oldDir, "ca-old", time.Now())
+	oldCA, err := (&TLSConfig{
+		CAFile:   filepath.Join(oldDir, "ca.crt"),
+		CertFile: client.CertFile,
+		KeyFile:  client.KeyFile,
+	}).ClientCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, checkHealth(addr, oldCA))
+}
+
+func serveHealth(t *testing.T, config *TLSConfig) string {
+	creds, err := config.ServerCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func checkHealth(addr string, creds credentials.TransportCredentials) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	_, err = healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(false))
+	return err
+}
+
+// writeCerts generates a self-signed CA, and server and client certificates signed by it.
+func writeCerts(t *testing.T, dir, caName string, modTime time.Time) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: caName},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(1 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "ca.crt"), "CERTIFICATE", caDER, modTime)
+
+	for i, name := range []string{"server", "client"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-1 * time.Hour),
+			NotAfter:     time.Now().Add(1 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writePEM(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der, modTime)
+		writePEM(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDER, modTime)
+	}
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte, modTime time.Time) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The reloader detects rotations by the modification time.
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,98 @@
+package omutils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+var watchLogger = logging.Component("watch")
+
+const (
+	watchInitialBackoff = 100 * time.Millisecond
+	watchMaxBackoff     = 5 * time.Second
+)
+
+// WatchAssignments calls onAssignment with each assignment of the ticket until ctx is done or onAssignment returns false.
+// The stream is re-subscribed when it breaks, e.g. by a restart of the frontend.
+// It returns the error of the stream that cannot be retried (e.g. NotFound for a deleted ticket).
+func WatchAssignments(ctx context.Context, omFrontend pb.FrontendServiceClient, ticketID string, onAssignment func(*pb.Assignment) bool) error {
+	backoff := watchInitialBackoff
+	for {
+		received, err := watchAssignmentsOnce(ctx, omFrontend, ticketID, onAssignment)
+		if err == nil || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !isRetryableWatchError(err) {
+			return err
+		}
+		if received {
+			backoff = watchInitialBackoff
+		}
+		watchLogger.Warn("watch assignments broken; re-subscribing", logging.TicketID(ticketID), "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > watchMaxBackoff {
+			backoff = watchMaxBackoff
+		}
+	}
+}
+
+// WaitForAssignment returns the first assignment of the ticket, re-subscribing like WatchAssignments.
+func WaitForAssignment(ctx context.Context, omFrontend pb.FrontendServiceClient, ticketID string) (*pb.Assignment, error) {
+	var assignment *pb.Assignment
+	err := WatchAssignments(ctx, omFrontend, ticketID, func(as *pb.Assignment) bool {
+		if as.GetConnection() == "" {
+			return true
+		}
+		assignment = as
+		return false
+	})
+	if assignment != nil {
+		return assignment, nil
+	}
+	return nil, err
+}
+
+// watchAssignmentsOnce returns nil when onAssignment stops the watch,
+// and reports whether any assignment is received before the stream breaks.
+func watchAssignmentsOnce(ctx context.Context, omFrontend pb.FrontendServiceClient, ticketID string, onAssignment func(*pb.Assignment) bool) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := omFrontend.WatchAssignments(ctx, &pb.WatchAssignmentsRequest{TicketId: ticketID})
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		if !onAssignment(resp.Assignment) {
+			return true, nil
+		}
+	}
+}
+
+func isRetryableWatchError(err error) bool {
+	// The frontend closes the stream on shutdown.
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package omutils
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// fakeFrontend serves WatchAssignments with a sequence of streams.
+type fakeFrontend struct {
+	pb.FrontendServiceClient
+	streams []*fakeWatchStream
+	watched int
+}
+
+func (f *fakeFrontend) WatchAssignments(ctx context.Context, in *pb.WatchAssignmentsRequest, opts ...grpc.CallOption) (pb.FrontendService_WatchAssignmentsClient, error) {
+	if f.watched >= len(f.streams) {
+		return nil, status.Error(codes.NotFound, "ticket not found")
+	}
+	s := f.streams[f.watched]
+	f.watched++
+	return s, nil
+}
+
+// fakeWatchStream returns the assignments, then err.
+type fakeWatchStream struct {
+	grpc.ClientStream
+	assignments []*pb.Assignment
+	err         error
+}
+
+func (s *fakeWatchStream) Recv() (*pb.WatchAssignmentsResponse, error) {
+	if len(s.assignments) == 0 {
+		return nil, s.err
+	}
+	as := s.assignments[0]
+	s.assignments = s.assignments[1:]
+	return &pb.WatchAssignmentsResponse{Assignment: as}, nil
+}
+
+func TestWaitForAssignment(t *testing.T) {
+	fe := &fakeFrontend{streams: []*fakeWatchStream{
+		{err: status.Error(codes.Unavailable, "frontend restarting")},
+		{err: io.EOF},
+		{assignments: []*pb.Assignment{{Connection: "gs-1"}}},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	as, err := WaitForAssignment(ctx, fe, "ticket-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs-1", as.Connection)
+	assert.Equal(t, 3, fe.watched)
+}
+
+func TestWatchAssignmentsNotRetryable(t *testing.T) {
+	fe := &fakeFrontend{streams: []*fakeWatchStream{
+		{assignments: []*pb.Assignment{{Connection: "gs-1"}}, err: status.Error(codes.Unavailable, "frontend restarting")},
+	}}
+	var connections []string
+	err := WatchAssignments(context.Background(), fe, "ticket-1", func(as *pb.Assignment) bool {
+		connections = append(connections, as.Connection)
+		return true
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, []string{"gs-1"}, connections)
+}
@@ -2,13 +2,13 @@ package tests
 
 import (
 	"context"
-	"fmt"
 	"testing"
 	"time"
 
 	"github.com/castaneai/openmatch-local-dev/omutils"
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"open-match.dev/open-match/pkg/pb"
 )
 
@@ -21,15 +21,13 @@ func TestCreateTicketWithBackfill(t *testing.T) {
 		omBackend:  backend,
 	}
 
-	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{
-		// Create a unique pool name for each test to avoid mixing with tickets created during previous tests.
-		{Name: fmt.Sprintf("test-pool-%s", uuid.Must(uuid.NewRandom()))},
-	}}
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
 
 	var allocatedGameServer *GameServer
 	var assignment *pb.Assignment
 
-	ticket1 := mustCreateTicket(t, frontend, &pb.Ticket{})
+	ticket1 := mustCreateTicket(t, frontend, newTicketInPool(pool))
 	{
 		matches, err := director.FetchMatches(ctx, profile, mfConfig)
 		assert.NoError(t, err)
@@ -49,11 +47,11 @@ func TestCreateTicketWithBackfill(t *testing.T) {
 		gs, ok := getGameServer(GameServerConnectionName(assignment.Connection))
 		assert.True(t, ok)
 		allocatedGameServer = gs
-		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket1.Id))
+		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket1.Id, assignment))
 		assert.Equal(t, string(allocatedGameServer.ConnectionName()), assignment.Connection)
 	}
 
-	ticket2 := mustCreateTicket(t, frontend, &pb.Ticket{})
+	ticket2 := mustCreateTicket(t, frontend, newTicketInPool(pool))
 	{
 		matches, err := director.FetchMatches(ctx, profile, mfConfig)
 		assert.NoError(t, err)
@@ -71,10 +69,10 @@ func TestCreateTicketWithBackfill(t *testing.T) {
 		assignment := mustAssignment(t, frontend, ticket2.Id, 3*time.Second)
 		assert.Equal(t, string(allocatedGameServer.ConnectionName()), assignment.Connection)
 
-		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket2.Id))
+		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket2.Id, assignment))
 	}
 
-	ticket3 := mustCreateTicket(t, frontend, &pb.Ticket{})
+	ticket3 := mustCreateTicket(t, frontend, newTicketInPool(pool))
 	{
 		matches, err := director.FetchMatches(ctx, profile, mfConfig)
 		assert.NoError(t, err)
@@ -92,16 +90,13 @@ func TestCreateTicketWithBackfill(t *testing.T) {
 		assignment := mustAssignment(t, frontend, ticket3.Id, 3*time.Second)
 		assert.Equal(t, string(allocatedGameServer.ConnectionName()), assignment.Connection)
 
-		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket3.Id))
+		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket3.Id, assignment))
 	}
 
-	assert.NoError(t, allocatedGameServer.StopBackfill())
+	// The GameServer re-opens the slot by itself when a player leaves.
 	assert.NoError(t, allocatedGameServer.DisconnectPlayer(ctx, ticket1.Id))
-	bf, err := allocatedGameServer.CreateBackfill(ctx, 1)
-	assert.NoError(t, err)
-	allocatedGameServer.StartBackfill(bf, assignment)
 
-	ticket4 := mustCreateTicket(t, frontend, &pb.Ticket{})
+	ticket4 := mustCreateTicket(t, frontend, newTicketInPool(pool))
 	{
 		matches, err := director.FetchMatches(ctx, profile, mfConfig)
 		assert.NoError(t, err)
@@ -118,6 +113,60 @@ func TestCreateTicketWithBackfill(t *testing.T) {
 
 		assignment := mustAssignment(t, frontend, ticket4.Id, 3*time.Second)
 		assert.Equal(t, string(allocatedGameServer.ConnectionName()), assignment.Connection)
-		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket4.Id))
+		assert.NoError(t, allocatedGameServer.ConnectPlayer(ctx, ticket4.Id, assignment))
+	}
+}
+
+func TestNoBackfillAfterLateJoinWindow(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend:     frontend,
+		omBackend:      backend,
+		lateJoinWindow: 500 * time.Millisecond,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+
+	tickets := []*pb.Ticket{
+		mustCreateTicket(t, frontend, newTicketInPool(pool)),
+		mustCreateTicket(t, frontend, newTicketInPool(pool)),
+	}
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if !assert.Len(t, matches, 1) || !assert.NotNil(t, matches[0].Backfill) {
+		return
+	}
+	backfillID := matches[0].Backfill.Id
+	_, err = director.AssignTickets(ctx, matches)
+	assert.NoError(t, err)
+
+	assignment := mustAssignment(t, frontend, tickets[0].Id, 3*time.Second)
+	gs, ok := getGameServer(GameServerConnectionName(assignment.Connection))
+	if !assert.True(t, ok) {
+		return
+	}
+	for _, ticket := range tickets {
+		assert.NoError(t, gs.ConnectPlayer(ctx, ticket.Id, mustAssignment(t, frontend, ticket.Id, 3*time.Second)))
+	}
+
+	// The backfill is deleted when the window closes, even though nobody has left.
+	assert.Eventually(t, func() bool {
+		_, err := frontend.GetBackfill(ctx, &pb.GetBackfillRequest{BackfillId: backfillID})
+		return status.Code(err) == codes.NotFound
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.False(t, gs.acceptsLateJoins())
+
+	// A player leaving doesn't re-open the slot either.
+	assert.NoError(t, gs.DisconnectPlayer(ctx, tickets[0].Id))
+
+	// The match is past the late join window, so a new ticket gets a new GameServer.
+	mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err = director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.True(t, matches[0].AllocateGameserver)
 	}
 }
@@ -2,17 +2,34 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
+	"github.com/castaneai/openmatch-local-dev/omutils"
 	"open-match.dev/open-match/pkg/pb"
 )
 
 type Director struct {
 	omFrontend pb.FrontendServiceClient
 	omBackend  pb.BackendServiceClient
+	// lateJoinWindow is how long allocated GameServers accept new players via backfill; see allocateGameServer.
+	lateJoinWindow time.Duration
+	// gameServerStartupDelay is how long an allocated GameServer takes to become ready.
+	gameServerStartupDelay time.Duration
+	// readyTimeout is how long to wait for an allocated GameServer to become ready (default 5s).
+	readyTimeout time.Duration
+	// rosterTimeout is how long an allocated GameServer reserves the slots for the players of the match (default 30s).
+	rosterTimeout time.Duration
 }
 
+const (
+	defaultReadyTimeout  = 5 * time.Second
+	defaultRosterTimeout = 30 * time.Second
+)
+
 func (d *Director) FetchMatches(ctx context.Context, profile *pb.MatchProfile, mfConfig *pb.FunctionConfig) ([]*pb.Match, error) {
 	stream, err := d.omBackend.FetchMatches(ctx, &pb.FetchMatchesRequest{Config: mfConfig, Profile: profile})
 	if err != nil {
@@ -32,31 +49,121 @@ func (d *Director) FetchMatches(ctx context.Context, profile *pb.MatchProfile, m
 	return matches, nil
 }
 
+// AssignTickets allocates game servers for the matches and assigns them in two phases:
+// the tickets are assigned only after the game server is ready and has accepted the roster of the match.
+// The matches whose game servers don't become ready in time, or can't be assigned (e.g. failing to sign join tokens),
+// are returned as *omutils.AllocationError after their tickets are released and the game servers are freed.
+// If Open Match fails to assign some tickets, it returns *omutils.AssignmentFailuresError
+// after releasing the game servers that none of the tickets will join.
+// A failure to release a match doesn't stop the others from being released or assigned; the errors are joined.
 func (d *Director) AssignTickets(ctx context.Context, matches []*pb.Match) ([]*pb.AssignmentGroup, error) {
-	var asgs []*pb.AssignmentGroup
+	var allocated []*pb.Match
 	for _, match := range matches {
 		// https://github.com/googleforgames/open-match/issues/1240#issuecomment-769898964
 		if match.AllocateGameserver {
-			gs := allocateGameServer(d.omFrontend)
-			as := &pb.Assignment{
-				Connection: string(gs.ConnectionName()),
-			}
-			asgs = append(asgs, &pb.AssignmentGroup{
-				TicketIds:  ticketIDs(match),
-				Assignment: as,
-			})
-			if match.Backfill != nil {
-				gs.StartBackfill(match.Backfill, as)
-			}
+			allocated = append(allocated, match)
 		} else {
 			// AssignTickets does nothing;
 			// wait for the Assignment to be conveyed by AcknowledgeBackfill.
 		}
 	}
-	if _, err := d.omBackend.AssignTickets(ctx, &pb.AssignTicketsRequest{Assignments: asgs}); err != nil {
-		return nil, fmt.Errorf("failed to assign tickets: %w", err)
+
+	// The game servers start up in parallel.
+	gameServers := make([]*GameServer, len(allocated))
+	errs := make([]error, len(allocated))
+	var wg sync.WaitGroup
+	for i, match := range allocated {
+		wg.Add(1)
+		go func(i int, match *pb.Match) {
+			defer wg.Done()
+			gameServers[i], errs[i] = d.prepareGameServer(ctx, match)
+		}(i, match)
+	}
+	wg.Wait()
+
+	var asgs []*pb.AssignmentGroup
+	var prepared []*GameServer
+	var preparedMatches []*pb.Match
+	var unallocated []*pb.Match
+	var allocErrs, releaseErrs []error
+	// release returns the tickets of the match to the pool and keeps going, so that one failure
+	// doesn't leave the other matches pending.
+	release := func(match *pb.Match) {
+		if err := omutils.ReleaseMatch(ctx, d.omBackend, d.omFrontend, match); err != nil {
+			releaseErrs = append(releaseErrs, fmt.Errorf("failed to release match %s: %w", match.MatchId, err))
+		}
+	}
+	for i, match := range allocated {
+		if errs[i] != nil {
+			release(match)
+			unallocated = append(unallocated, match)
+			allocErrs = append(allocErrs, errs[i])
+			continue
+		}
+		gs := gameServers[i]
+		groups, err := omutils.AssignMatch(match, string(gs.ConnectionName()), joinTokenSigner)
+		if err != nil {
+			// The match can't be assigned to the game server, as if none had been allocated.
+			deallocateGameServer(gs.ConnectionName())
+			release(match)
+			unallocated = append(unallocated, match)
+			allocErrs = append(allocErrs, err)
+			continue
+		}
+		asgs = append(asgs, groups...)
+		prepared = append(prepared, gs)
+		preparedMatches = append(preparedMatches, match)
+		if match.Backfill != nil {
+			gs.StartBackfill(match.Backfill, gs.Assignment())
+		}
+	}
+	var allocErr error
+	if len(unallocated) > 0 {
+		allocErr = &omutils.AllocationError{Matches: unallocated, Err: errors.Join(allocErrs...)}
+	}
+
+	err := omutils.AssignTickets(ctx, d.omBackend, asgs)
+	var failures *omutils.AssignmentFailuresError
+	switch {
+	case errors.As(err, &failures):
+		for _, asg := range failures.FailedGroups(asgs) {
+			deallocateGameServer(GameServerConnectionName(asg.Assignment.Connection))
+		}
+	case err != nil:
+		// Nobody is assigned to the prepared game servers.
+		for _, gs := range prepared {
+			deallocateGameServer(gs.ConnectionName())
+		}
+		for _, match := range preparedMatches {
+			release(match)
+		}
+	}
+	return asgs, errors.Join(allocErr, errors.Join(releaseErrs...), err)
+}
+
+// prepareGameServer allocates a game server for the match and waits until it is ready and has accepted the roster.
+// The game server is freed on failure.
+func (d *Director) prepareGameServer(ctx context.Context, match *pb.Match) (*GameServer, error) {
+	gs := allocateGameServer(d.omFrontend, d.lateJoinWindow, d.gameServerStartupDelay)
+	timeout := d.readyTimeout
+	if timeout == 0 {
+		timeout = defaultReadyTimeout
+	}
+	rosterTimeout := d.rosterTimeout
+	if rosterTimeout == 0 {
+		rosterTimeout = defaultRosterTimeout
+	}
+	readyCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := gs.WaitForReady(readyCtx)
+	if err == nil {
+		err = gs.AcceptRoster(match.MatchId, ticketIDs(match), rosterTimeout)
+	}
+	if err != nil {
+		deallocateGameServer(gs.ConnectionName())
+		return nil, fmt.Errorf("failed to prepare gameserver %s: %w", gs.ConnectionName(), err)
 	}
-	return asgs, nil
+	return gs, nil
 }
 
 func ticketIDs(match *pb.Match) []string {
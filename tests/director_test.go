@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestAssignDeletedTicket(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend: frontend,
+		omBackend:  backend,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+
+	ticket := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	// The player cancels matchmaking while the match is being made.
+	_, err = frontend.DeleteTicket(ctx, &pb.DeleteTicketRequest{TicketId: ticket.Id})
+	assert.NoError(t, err)
+
+	asgs, err := director.AssignTickets(ctx, matches)
+	var failures *omutils.AssignmentFailuresError
+	if assert.True(t, errors.As(err, &failures)) {
+		assert.Len(t, failures.Failures, 1)
+		assert.Equal(t, ticket.Id, failures.Failures[0].TicketId)
+		assert.Equal(t, pb.AssignmentFailure_TICKET_NOT_FOUND, failures.Failures[0].Cause)
+	}
+	// The game server allocated for the match is released.
+	if assert.Len(t, asgs, 1) {
+		_, ok := getGameServer(GameServerConnectionName(asgs[0].Assignment.Connection))
+		assert.False(t, ok)
+	}
+}
+
+func TestAssignGameServerNotReady(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend:             frontend,
+		omBackend:              backend,
+		gameServerStartupDelay: 5 * time.Second,
+		readyTimeout:           100 * time.Millisecond,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+
+	ticket := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	asgs, err := director.AssignTickets(ctx, matches)
+	var allocErr *omutils.AllocationError
+	if assert.True(t, errors.As(err, &allocErr)) {
+		assert.ErrorIs(t, err, ErrGameServerNotReady)
+		assert.Len(t, allocErr.Matches, 1)
+	}
+	assert.Empty(t, asgs)
+	_, err = waitForAssignment(frontend, ticket.Id, 500*time.Millisecond)
+	assert.Error(t, err)
+
+	// The ticket goes straight back into the pool without waiting for the pending timeout.
+	director.gameServerStartupDelay = 0
+	matches, err = director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, ticket.Id, matches[0].Tickets[0].Id)
+	}
+	_, err = director.AssignTickets(ctx, matches)
+	assert.NoError(t, err)
+	as := mustAssignment(t, frontend, ticket.Id, 3*time.Second)
+	gs, ok := getGameServer(GameServerConnectionName(as.Connection))
+	if assert.True(t, ok) {
+		assert.NoError(t, gs.ConnectPlayer(ctx, ticket.Id, as))
+	}
+}
+
+func TestConnectPlayerNotAssigned(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend: frontend,
+		omBackend:  backend,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+
+	ticket := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	_, err = director.AssignTickets(ctx, matches)
+	assert.NoError(t, err)
+	as := mustAssignment(t, frontend, ticket.Id, 3*time.Second)
+	info, err := omutils.GetAssignmentInfo(as)
+	assert.NoError(t, err)
+	assert.Equal(t, matches[0].MatchId, info.MatchID)
+	assert.NotEmpty(t, info.JoinToken)
+	gs, ok := getGameServer(GameServerConnectionName(as.Connection))
+	if !assert.True(t, ok) {
+		return
+	}
+
+	// A ticket in the pool that is not assigned to the game server
+	other := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	assert.ErrorIs(t, gs.ConnectPlayer(ctx, other.Id, as), ErrPlayerNotAssigned)
+	assert.ErrorIs(t, gs.ConnectPlayer(ctx, other.Id, &pb.Assignment{Connection: as.Connection}), ErrPlayerNotAssigned)
+	assert.ErrorIs(t, gs.ConnectPlayer(ctx, ticket.Id, &pb.Assignment{Connection: "another-gameserver"}), ErrPlayerNotAssigned)
+	// The player of the match can't skip the join token.
+	assert.ErrorIs(t, gs.ConnectPlayer(ctx, ticket.Id, &pb.Assignment{Connection: as.Connection}), ErrPlayerNotAssigned)
+	// A join token of another match
+	groups, err := omutils.AssignMatch(&pb.Match{MatchId: "another-match", Tickets: []*pb.Ticket{ticket}}, as.Connection, joinTokenSigner)
+	if assert.NoError(t, err) {
+		assert.ErrorIs(t, gs.ConnectPlayer(ctx, ticket.Id, groups[0].Assignment), ErrPlayerNotAssigned)
+	}
+	assert.NoError(t, gs.ConnectPlayer(ctx, ticket.Id, as))
+}
+
+func TestRosterExpires(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend:    frontend,
+		omBackend:     backend,
+		rosterTimeout: 500 * time.Millisecond,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+
+	ticket1 := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if !assert.Len(t, matches, 1) || !assert.NotNil(t, matches[0].Backfill) {
+		return
+	}
+	backfillID := matches[0].Backfill.Id
+	_, err = director.AssignTickets(ctx, matches)
+	assert.NoError(t, err)
+	as := mustAssignment(t, frontend, ticket1.Id, 3*time.Second)
+	gs, ok := getGameServer(GameServerConnectionName(as.Connection))
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.NoError(t, gs.ConnectPlayer(ctx, ticket1.Id, as))
+
+	// The other player never connects, so the slot reserved for it is re-opened via the backfill.
+	assert.Eventually(t, func() bool {
+		backfill, err := frontend.GetBackfill(ctx, &pb.GetBackfillRequest{BackfillId: backfillID})
+		if err != nil {
+			return false
+		}
+		openSlots, err := omutils.GetOpenSlots(backfill)
+		return err == nil && openSlots == int32(omutils.PlayersPerMatch-1)
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/castaneai/openmatch-local-dev/omutils/logging"
 	"github.com/google/uuid"
 	"open-match.dev/open-match/pkg/pb"
 )
@@ -21,8 +21,13 @@ const (
 
 var (
 	ErrGameServerCapacityExceeded = errors.New("gameserver capacity exceeded")
+	ErrGameServerNotReady         = errors.New("gameserver is not ready")
+	ErrPlayerNotAssigned          = errors.New("player is not assigned to the gameserver")
 )
 
+// joinTokenSigner is the key shared by the Director and the GameServers; it is created by TestMain.
+var joinTokenSigner *omutils.JoinTokenSigner
+
 type GameServerConnectionName string
 
 var gameServerMap = map[GameServerConnectionName]*GameServer{}
@@ -32,9 +37,21 @@ type GameServer struct {
 	omFrontend     pb.FrontendServiceClient
 	connectionName GameServerConnectionName
 	players        map[string]struct{}
+	capacity       int
 	mu             sync.RWMutex
-	logger         *log.Logger
+	logger         *slog.Logger
 	backfillAcker  atomic.Pointer[backfillAcker]
+	// searchFields of the backfill, so that a re-created backfill stays in the same pool.
+	searchFields atomic.Pointer[pb.SearchFields]
+	// lateJoinsClosed is set when the late join window is over;
+	// the GameServer no longer accepts new players via backfill.
+	lateJoinsClosed atomic.Bool
+	// ready is closed when the GameServer has started up.
+	ready chan struct{}
+	// matchID is the match hosted by the GameServer.
+	matchID string
+	// roster is the players of the match who are expected to connect.
+	roster map[string]struct{}
 }
 
 func getGameServer(name GameServerConnectionName) (*GameServer, bool) {
@@ -44,41 +61,205 @@ func getGameServer(name GameServerConnectionName) (*GameServer, bool) {
 	return gs, ok
 }
 
-func allocateGameServer(omFrontend pb.FrontendServiceClient) *GameServer {
+// allocateGameServer allocates a GameServer that becomes ready after startupDelay.
+// The GameServer accepts new players via backfill for lateJoinWindow after allocation, or always if it is zero.
+func allocateGameServer(omFrontend pb.FrontendServiceClient, lateJoinWindow, startupDelay time.Duration) *GameServer {
 	gameServerMapMu.Lock()
 	defer gameServerMapMu.Unlock()
 	connName := GameServerConnectionName(uuid.Must(uuid.NewRandom()).String())
-	logger := log.New(os.Stderr, fmt.Sprintf("[GS: %s] ", connName), log.LstdFlags)
-	gameServerMap[connName] = &GameServer{
+	logger := logging.Component("gameserver").With("connection", connName)
+	gs := &GameServer{
 		omFrontend:     omFrontend,
 		connectionName: connName,
 		players:        map[string]struct{}{},
+		capacity:       omutils.PlayersPerMatch,
 		mu:             sync.RWMutex{},
 		logger:         logger,
+		ready:          make(chan struct{}),
+		roster:         map[string]struct{}{},
+	}
+	gameServerMap[connName] = gs
+	if lateJoinWindow > 0 {
+		time.AfterFunc(lateJoinWindow, gs.closeLateJoins)
+	}
+	time.AfterFunc(startupDelay, func() {
+		close(gs.ready)
+		logger.Info("ready")
+	})
+	logger.Info("allocated")
+	return gs
+}
+
+// ownedBackfills returns the backfills of the live GameServers with the time they were last acknowledged;
+// it is the omutils.BackfillOwner of the GameServers.
+func ownedBackfills() map[string]time.Time {
+	gameServerMapMu.RLock()
+	defer gameServerMapMu.RUnlock()
+	owned := map[string]time.Time{}
+	for _, gs := range gameServerMap {
+		if w := gs.backfillAcker.Load(); w != nil && !w.Stopped() {
+			owned[w.backfill.Id] = w.LastAcknowledged()
+		}
+	}
+	return owned
+}
+
+// crashGameServer removes the GameServer without deleting its backfill, as if the process died.
+func crashGameServer(name GameServerConnectionName) {
+	gameServerMapMu.Lock()
+	gs, ok := gameServerMap[name]
+	delete(gameServerMap, name)
+	gameServerMapMu.Unlock()
+	if !ok {
+		return
+	}
+	if w := gs.backfillAcker.Load(); w != nil {
+		w.stop()
 	}
-	logger.Printf("allocated")
-	return gameServerMap[connName]
+	gs.logger.Info("crashed")
+}
+
+// deallocateGameServer stops the GameServer and its backfill, e.g. when none of its players were assigned.
+func deallocateGameServer(name GameServerConnectionName) {
+	gameServerMapMu.Lock()
+	gs, ok := gameServerMap[name]
+	delete(gameServerMap, name)
+	gameServerMapMu.Unlock()
+	if !ok {
+		return
+	}
+	_ = gs.StopBackfill()
+	gs.logger.Info("deallocated")
 }
 
 func (gs *GameServer) ConnectionName() GameServerConnectionName {
 	return gs.connectionName
 }
 
-func (gs *GameServer) ConnectPlayer(ctx context.Context, ticketID string) error {
+func (gs *GameServer) Assignment() *pb.Assignment {
+	return &pb.Assignment{Connection: string(gs.connectionName)}
+}
+
+// WaitForReady waits until the GameServer has started up.
+func (gs *GameServer) WaitForReady(ctx context.Context) error {
+	select {
+	case <-gs.ready:
+		return nil
+	case <-ctx.Done():
+		return ErrGameServerNotReady
+	}
+}
+
+// AcceptRoster reserves the slots for the players of the match before they are assigned.
+// The reservations of the players who don't connect within timeout are dropped.
+func (gs *GameServer) AcceptRoster(matchID string, ticketIDs []string, timeout time.Duration) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if len(gs.players)+len(gs.roster)+len(ticketIDs) > gs.capacity {
+		return ErrGameServerCapacityExceeded
+	}
+	gs.matchID = matchID
+	for _, id := range ticketIDs {
+		gs.roster[id] = struct{}{}
+	}
+	time.AfterFunc(timeout, func() { gs.expireRoster(ticketIDs) })
+	gs.logger.Info("roster accepted", logging.TicketIDs(ticketIDs))
+	return nil
+}
+
+// expireRoster drops the reservations of the players who haven't connected, and re-opens their slots.
+func (gs *GameServer) expireRoster(ticketIDs []string) {
+	if live, ok := getGameServer(gs.connectionName); !ok || live != gs {
+		return
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	var expired []string
+	for _, id := range ticketIDs {
+		if _, ok := gs.roster[id]; ok {
+			delete(gs.roster, id)
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	gs.logger.Info("roster expired", logging.TicketIDs(expired))
+	if err := gs.reopenSlots(context.Background()); err != nil {
+		gs.logger.Error("failed to re-open slots", "error", err)
+	}
+}
+
+// openSlots is the number of slots that are neither taken by the players nor reserved for the roster.
+func (gs *GameServer) openSlots() int {
+	return gs.capacity - len(gs.players) - len(gs.roster)
+}
+
+// ConnectPlayer accepts the player of the ticket with its Assignment,
+// rejecting tickets that were not assigned to this GameServer.
+func (gs *GameServer) ConnectPlayer(ctx context.Context, ticketID string, assignment *pb.Assignment) error {
+	if err := gs.verifyAssignment(ctx, ticketID, assignment); err != nil {
+		gs.logger.Warn("player rejected", logging.TicketID(ticketID), "error", err)
+		return err
+	}
+
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
 
 	if _, exists := gs.players[ticketID]; exists {
-		gs.log("player re-connected (ticketID: %s) (%d players in room)", ticketID, len(gs.players))
+		gs.logger.Info("player re-connected", logging.TicketID(ticketID), "players", len(gs.players))
 		return nil
 	}
 
 	newPlayerCount := len(gs.players) + 1
-	if newPlayerCount > omutils.PlayersPerMatch {
+	// The players in the roster have their slots reserved.
+	if _, reserved := gs.roster[ticketID]; reserved {
+		delete(gs.roster, ticketID)
+	} else if newPlayerCount+len(gs.roster) > gs.capacity {
 		return ErrGameServerCapacityExceeded
 	}
 	gs.players[ticketID] = struct{}{}
-	gs.log("player connected (ticketID: %s) (%d players in room)", ticketID, newPlayerCount)
+	gs.logger.Info("player connected", logging.TicketID(ticketID), "players", newPlayerCount)
+	return nil
+}
+
+func (gs *GameServer) verifyAssignment(ctx context.Context, ticketID string, assignment *pb.Assignment) error {
+	if assignment.GetConnection() != string(gs.connectionName) {
+		return fmt.Errorf("%w: connection '%s'", ErrPlayerNotAssigned, assignment.GetConnection())
+	}
+	info, err := omutils.GetAssignmentInfo(assignment)
+	if err != nil {
+		return err
+	}
+	gs.mu.RLock()
+	_, reserved := gs.roster[ticketID]
+	matchID := gs.matchID
+	gs.mu.RUnlock()
+	if info.JoinToken != "" {
+		claims, err := joinTokenSigner.Verify(info.JoinToken)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrPlayerNotAssigned, err)
+		}
+		if claims.TicketID != ticketID || claims.Connection != string(gs.connectionName) {
+			return fmt.Errorf("%w: join token is for ticket '%s' on '%s'", ErrPlayerNotAssigned, claims.TicketID, claims.Connection)
+		}
+		if claims.MatchID != matchID {
+			return fmt.Errorf("%w: join token is for match '%s'", ErrPlayerNotAssigned, claims.MatchID)
+		}
+		return nil
+	}
+	// The players of the match must present the join token signed by the Director.
+	if reserved {
+		return fmt.Errorf("%w: join token is required", ErrPlayerNotAssigned)
+	}
+	// The players joining via backfill have no join token, so ask Open Match where the ticket is assigned.
+	ticket, err := gs.omFrontend.GetTicket(ctx, &pb.GetTicketRequest{TicketId: ticketID})
+	if err != nil {
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if ticket.Assignment.GetConnection() != string(gs.connectionName) {
+		return fmt.Errorf("%w: ticket is assigned to '%s'", ErrPlayerNotAssigned, ticket.Assignment.GetConnection())
+	}
 	return nil
 }
 
@@ -91,13 +272,67 @@ func (gs *GameServer) DisconnectPlayer(ctx context.Context, ticketID string) err
 	}
 	delete(gs.players, ticketID)
 
-	newPlayerCount := len(gs.players)
-	gs.log("player disconnected (ticketID: %s) (%d players in room)", ticketID, newPlayerCount)
+	gs.logger.Info("player disconnected", logging.TicketID(ticketID), "players", len(gs.players))
+	return gs.reopenSlots(ctx)
+}
+
+// reopenSlots advertises the open slots via backfill,
+// unless the match is too far along for new players to join.
+func (gs *GameServer) reopenSlots(ctx context.Context) error {
+	if !gs.acceptsLateJoins() {
+		gs.logger.Info("late joins are no longer accepted; stop backfilling")
+		return gs.StopBackfill()
+	}
+	return gs.ensureBackfill(ctx, gs.openSlots())
+}
+
+func (gs *GameServer) acceptsLateJoins() bool {
+	return !gs.lateJoinsClosed.Load()
+}
+
+// closeLateJoins stops backfilling when the late join window is over,
+// so that new players stop joining even if nobody leaves the GameServer.
+func (gs *GameServer) closeLateJoins() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.lateJoinsClosed.Store(true)
+	if w := gs.backfillAcker.Load(); w != nil && !w.Stopped() {
+		gs.logger.Info("late join window is over; stop backfilling")
+		_ = gs.StopBackfill()
+	}
+}
+
+// ensureBackfill updates the running backfill to the given openSlots,
+// or creates and starts a new one if the GameServer is not backfilling.
+func (gs *GameServer) ensureBackfill(ctx context.Context, openSlots int) error {
+	if w := gs.backfillAcker.Load(); w != nil && !w.Stopped() {
+		return gs.updateBackfill(ctx, w.backfill.Id, openSlots)
+	}
+	backfill, err := gs.CreateBackfill(ctx, openSlots)
+	if err != nil {
+		return err
+	}
+	gs.startBackfill(backfill, gs.Assignment())
+	return nil
+}
+
+func (gs *GameServer) updateBackfill(ctx context.Context, backfillID string, openSlots int) error {
+	backfill, err := gs.omFrontend.GetBackfill(ctx, &pb.GetBackfillRequest{BackfillId: backfillID})
+	if err != nil {
+		return err
+	}
+	if err := omutils.SetOpenSlots(backfill, int32(openSlots)); err != nil {
+		return err
+	}
+	if _, err := gs.omFrontend.UpdateBackfill(ctx, &pb.UpdateBackfillRequest{Backfill: backfill}); err != nil {
+		return err
+	}
+	gs.logger.Info("backfill updated", logging.BackfillID(backfillID), "open_slots", openSlots)
 	return nil
 }
 
 func (gs *GameServer) CreateBackfill(ctx context.Context, openSlots int) (*pb.Backfill, error) {
-	req := &pb.Backfill{}
+	req := &pb.Backfill{SearchFields: gs.searchFields.Load()}
 	if err := omutils.SetOpenSlots(req, int32(openSlots)); err != nil {
 		return nil, err
 	}
@@ -105,15 +340,28 @@ func (gs *GameServer) CreateBackfill(ctx context.Context, openSlots int) (*pb.Ba
 	if err != nil {
 		return nil, err
 	}
-	gs.log("backfill created (backfillID: %s, openSlots: %d)", backfill.Id, openSlots)
+	gs.logger.Info("backfill created", logging.BackfillID(backfill.Id), "open_slots", openSlots)
 	return backfill, nil
 }
 
+// StartBackfill starts acknowledging the backfill, or deletes it if the late join window is already over.
 func (gs *GameServer) StartBackfill(backfill *pb.Backfill, assignment *pb.Assignment) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if !gs.acceptsLateJoins() {
+		gs.logger.Info("late join window is over; delete backfill", logging.BackfillID(backfill.Id))
+		_, _ = gs.omFrontend.DeleteBackfill(context.Background(), &pb.DeleteBackfillRequest{BackfillId: backfill.Id})
+		return
+	}
+	gs.startBackfill(backfill, assignment)
+}
+
+func (gs *GameServer) startBackfill(backfill *pb.Backfill, assignment *pb.Assignment) {
 	// The allocated GameServer starts polling Open Match to acknowledge the backfill
 	// ref: https://open-match.dev/site/docs/guides/backfill/
+	gs.searchFields.Store(backfill.SearchFields)
 	gs.backfillAcker.Store(startBackfillAcker(gs.omFrontend, backfill, assignment))
-	gs.log("start polling with acknowledge backfill")
+	gs.logger.Info("start polling with acknowledge backfill", logging.BackfillID(backfill.Id))
 }
 
 func (gs *GameServer) StopBackfill() error {
@@ -123,18 +371,23 @@ func (gs *GameServer) StopBackfill() error {
 	return nil
 }
 
-func (gs *GameServer) log(format string, args ...interface{}) {
-	gs.logger.Printf(format, args...)
-}
-
 type backfillAcker struct {
 	backfill   *pb.Backfill
 	omFrontend pb.FrontendServiceClient
+	ctx        context.Context
 	stop       context.CancelFunc
+	// lastAcknowledged is the UnixNano of the last successful AcknowledgeBackfill.
+	lastAcknowledged atomic.Int64
 }
 
 func startBackfillAcker(omFrontend pb.FrontendServiceClient, backfill *pb.Backfill, assignment *pb.Assignment) *backfillAcker {
 	ctx, stop := context.WithCancel(context.Background())
+	b := &backfillAcker{
+		backfill:   backfill,
+		omFrontend: omFrontend,
+		ctx:        ctx,
+		stop:       stop,
+	}
 	go func() {
 		ticker := time.NewTicker(acknowledgeBackfillInterval)
 		defer ticker.Stop()
@@ -152,14 +405,24 @@ func startBackfillAcker(omFrontend pb.FrontendServiceClient, backfill *pb.Backfi
 					}
 					continue
 				}
+				b.lastAcknowledged.Store(time.Now().UnixNano())
 			}
 		}
 	}()
-	return &backfillAcker{
-		backfill:   backfill,
-		omFrontend: omFrontend,
-		stop:       stop,
+	return b
+}
+
+// LastAcknowledged returns the time of the last successful AcknowledgeBackfill, or zero if not yet.
+func (b *backfillAcker) LastAcknowledged() time.Time {
+	nanos := b.lastAcknowledged.Load()
+	if nanos == 0 {
+		return time.Time{}
 	}
+	return time.Unix(0, nanos)
+}
+
+func (b *backfillAcker) Stopped() bool {
+	return b.ctx.Err() != nil
 }
 
 func (b *backfillAcker) Stop() {
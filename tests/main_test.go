@@ -2,11 +2,13 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/google/uuid"
 	"open-match.dev/open-match/pkg/pb"
 )
 
@@ -19,6 +21,7 @@ const (
 	// See portForward section in skaffold.yaml
 	frontendAddr = "localhost:50504"
 	backendAddr  = "localhost:50505"
+	queryAddr    = "localhost:50503"
 )
 
 var mfConfig = &pb.FunctionConfig{
@@ -27,20 +30,65 @@ var mfConfig = &pb.FunctionConfig{
 	Type: pb.FunctionConfig_GRPC,
 }
 
+func TestMain(m *testing.M) {
+	signer, err := omutils.NewRandomJoinTokenSigner()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create join token signer: %+v\n", err)
+		os.Exit(1)
+	}
+	joinTokenSigner = signer
+	os.Exit(m.Run())
+}
+
 func newOMFrontendClient(t *testing.T) pb.FrontendServiceClient {
-	cc, err := grpc.Dial(frontendAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c, err := omutils.NewOMFrontendClient(frontendAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return pb.NewFrontendServiceClient(cc)
+	return c
 }
 
 func newOMBackendClient(t *testing.T) pb.BackendServiceClient {
-	cc, err := grpc.Dial(backendAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c, err := omutils.NewOMBackendClient(backendAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newOMQueryClient(t *testing.T) pb.QueryServiceClient {
+	c, err := omutils.NewOMQueryClient(queryAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return pb.NewBackendServiceClient(cc)
+	return c
+}
+
+// newTestPool returns a pool of the tickets with a unique tag for each test,
+// to avoid mixing with tickets created during other tests.
+// The tickets and backfills in the pool are deleted when the test finishes.
+func newTestPool(t *testing.T, fe pb.FrontendServiceClient) *pb.Pool {
+	t.Helper()
+	tag := fmt.Sprintf("test-%s", uuid.Must(uuid.NewRandom()))
+	pool := &pb.Pool{
+		Name:              fmt.Sprintf("test-pool-%s", tag),
+		TagPresentFilters: []*pb.TagPresentFilter{{Tag: tag}},
+	}
+	qs := newOMQueryClient(t)
+	t.Cleanup(func() {
+		tickets, backfills, err := omutils.CleanupPool(context.Background(), fe, qs, pool)
+		if err != nil {
+			t.Errorf("failed to cleanup pool: %+v", err)
+			return
+		}
+		t.Logf("cleanup pool %s (tickets: %d, backfills: %d)", pool.Name, tickets, backfills)
+	})
+	return pool
+}
+
+// newTicketInPool returns a ticket that matches the filters of the pool.
+func newTicketInPool(pool *pb.Pool) *pb.Ticket {
+	return &pb.Ticket{SearchFields: omutils.SearchFieldsForPool(pool)}
 }
 
 func mustCreateTicket(t *testing.T, fe pb.FrontendServiceClient, ticket *pb.Ticket) *pb.Ticket {
@@ -66,15 +114,5 @@ func mustAssignment(t *testing.T, fe pb.FrontendServiceClient, ticketID string,
 func waitForAssignment(fe pb.FrontendServiceClient, ticketID string, timeout time.Duration) (*pb.Assignment, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	stream, err := fe.WatchAssignments(ctx, &pb.WatchAssignmentsRequest{TicketId: ticketID})
-	if err != nil {
-		return nil, err
-	}
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			return nil, err
-		}
-		return resp.Assignment, nil
-	}
+	return omutils.WaitForAssignment(ctx, fe, ticketID)
 }
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/castaneai/openmatch-local-dev/omutils"
+	"github.com/stretchr/testify/assert"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+func TestReapOrphanedBackfill(t *testing.T) {
+	ctx := context.Background()
+	frontend := newOMFrontendClient(t)
+	backend := newOMBackendClient(t)
+	director := &Director{
+		omFrontend: frontend,
+		omBackend:  backend,
+	}
+
+	pool := newTestPool(t, frontend)
+	profile := &pb.MatchProfile{Name: "test-profile", Pools: []*pb.Pool{pool}}
+	reaper, err := omutils.NewBackfillReaper(frontend, newOMQueryClient(t), omutils.BackfillOwnerFunc(ownedBackfills), &omutils.BackfillReaperConfig{
+		Pools:       []*pb.Pool{pool},
+		GracePeriod: 100 * time.Millisecond,
+		AckDeadline: 1 * time.Second,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ticket1 := mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err := director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if !assert.Len(t, matches, 1) || !assert.NotNil(t, matches[0].Backfill) {
+		return
+	}
+	backfillID := matches[0].Backfill.Id
+	_, err = director.AssignTickets(ctx, matches)
+	assert.NoError(t, err)
+	assignment := mustAssignment(t, frontend, ticket1.Id, 3*time.Second)
+
+	// The backfill of the live game server is kept.
+	time.Sleep(200 * time.Millisecond)
+	result, err := reaper.ReapOnce(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Deleted)
+	assert.Empty(t, result.Unacknowledged)
+
+	crashGameServer(GameServerConnectionName(assignment.Connection))
+	result, err = reaper.ReapOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{backfillID}, result.Deleted)
+
+	// A new ticket is not routed to the dead game server.
+	mustCreateTicket(t, frontend, newTicketInPool(pool))
+	matches, err = director.FetchMatches(ctx, profile, mfConfig)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.True(t, matches[0].AllocateGameserver)
+	}
+}
+
+func TestReapUnfilteredPool(t *testing.T) {
+	// It fails before calling Open Match, so that the backfills of other tests are not deleted.
+	_, err := omutils.NewBackfillReaper(nil, nil, omutils.BackfillOwnerFunc(ownedBackfills), &omutils.BackfillReaperConfig{
+		Pools: []*pb.Pool{{Name: "all"}},
+	})
+	assert.ErrorIs(t, err, omutils.ErrUnfilteredPool)
+}